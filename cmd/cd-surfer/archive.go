@@ -0,0 +1,510 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/andriykrefer/cdsurfer/config"
+	"github.com/andriykrefer/cdsurfer/term"
+	"github.com/dustin/go-humanize"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Archives are browsed as read-only folders. Their index is read when they are opened, and the
+// contents of the entries are read again from the archive file when previewed or extracted
+
+var errInsideArchive = errors.New("not available inside archives")
+
+// errStopWalk stops walkArchive without error
+var errStopWalk = errors.New("stop walk")
+
+const archiveMaxLinkSz = 4096 // Zip symlinks store the target as the content
+
+// archiveFormats maps the file name suffixes to the archive formats
+var archiveFormats = []struct {
+	suffix, format string
+}{
+	{".zip", "zip"},
+	{".tar", "tar"},
+	{".tar.gz", "tar.gz"},
+	{".tgz", "tar.gz"},
+	{".tar.zst", "tar.zst"},
+	{".tzst", "tar.zst"},
+}
+
+// archiveFormat returns the archive format of a file name, or "" if it is not an archive
+func archiveFormat(name string) string {
+	_, format := archiveSuffix(name)
+	return format
+}
+
+// archiveStem returns the archive name without the archive suffix, like "a" for "a.tar.gz"
+func archiveStem(name string) string {
+	suffixLen, _ := archiveSuffix(name)
+	return name[:len(name)-suffixLen]
+}
+
+func archiveSuffix(name string) (suffixLen int, format string) {
+	lower := strings.ToLower(name)
+	for _, f := range archiveFormats {
+		if strings.HasSuffix(lower, f.suffix) && len(lower) > len(f.suffix) {
+			return len(f.suffix), f.format
+		}
+	}
+	return 0, ""
+}
+
+// archiveEntry is a file or folder inside an archive
+type archiveEntry struct {
+	name       string // Path inside the archive, like "dir/file.txt"
+	info       os.FileInfo
+	linkTarget string // != "" for symlinks
+	hardLink   string // != "" for tar hard links: the name of the entry they link to
+	unsafe     bool   // The stored name (or hard link target) is absolute or has "..", so it is not extracted
+}
+
+type archive struct {
+	path     string                    // Absolute path of the archive file
+	entries  map[string]archiveEntry   // Path inside the archive -> entry
+	children map[string][]archiveEntry // Folder path inside the archive ("" for the root) -> its entries
+}
+
+// virtualDirInfo is the info of folders that are not on the disk, like the archive folders that are not
+// stored on the archive, only their contents
+type virtualDirInfo struct {
+	name string
+}
+
+func (d virtualDirInfo) Name() string       { return d.name }
+func (d virtualDirInfo) Size() int64        { return 0 }
+func (d virtualDirInfo) Mode() os.FileMode  { return os.ModeDir | 0755 }
+func (d virtualDirInfo) ModTime() time.Time { return time.Time{} }
+func (d virtualDirInfo) IsDir() bool        { return true }
+func (d virtualDirInfo) Sys() interface{}   { return nil }
+
+// renamedInfo is the info of another file, with a different name
+type renamedInfo struct {
+	os.FileInfo
+	name string
+}
+
+func (r renamedInfo) Name() string { return r.name }
+
+// cleanArchiveName returns the path of an entry relative to the archive root. Leading slashes and ".."
+// are removed, so entries cannot point outside the archive. It returns "" for the root
+func cleanArchiveName(name string) string {
+	return strings.TrimPrefix(path.Clean("/"+strings.ReplaceAll(name, `\`, "/")), "/")
+}
+
+// isUnsafeArchiveName returns true for names that would be extracted outside of the destination
+func isUnsafeArchiveName(name string) bool {
+	name = strings.ReplaceAll(name, `\`, "/")
+	if path.IsAbs(name) {
+		return true
+	}
+	for _, part := range strings.Split(name, "/") {
+		if part == ".." {
+			return true
+		}
+	}
+	return false
+}
+
+// walkArchive calls fn for each entry of the archive, in the archive order. open returns the contents
+// of the entry. The walk stops when fn returns an error, which is returned unless it is errStopWalk, or
+// when ctx is canceled. read, if not nil, is increased with the bytes read from the archive file
+func walkArchive(ctx context.Context, archivePath string, read *int64,
+	fn func(e archiveEntry, open func() (io.ReadCloser, error)) error) error {
+	format := archiveFormat(archivePath)
+	if format == "" {
+		return fmt.Errorf("%s is not an archive", filepath.Base(archivePath))
+	}
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if read == nil {
+		read = new(int64)
+	}
+	pf := &progressFile{ctx: ctx, f: f, read: read}
+
+	if format == "zip" {
+		r, err := zip.NewReader(pf, info.Size())
+		if err != nil {
+			return err
+		}
+		for _, f := range r.File {
+			e := archiveEntry{name: cleanArchiveName(f.Name), info: f.FileInfo(), unsafe: isUnsafeArchiveName(f.Name)}
+			if e.info.Mode()&os.ModeSymlink != 0 {
+				e.linkTarget = readZipLink(f)
+			}
+			if err := fn(e, f.Open); err != nil {
+				return ignoreStopWalk(err)
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var r io.Reader = pf
+	switch format {
+	case "tar.gz":
+		gz, err := gzip.NewReader(pf)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	case "tar.zst":
+		zr, err := zstd.NewReader(pf)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		r = zr
+	}
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		e := archiveEntry{
+			name:       cleanArchiveName(hdr.Name),
+			info:       hdr.FileInfo(),
+			linkTarget: hdr.Linkname,
+			unsafe:     isUnsafeArchiveName(hdr.Name),
+		}
+		if hdr.Typeflag == tar.TypeLink {
+			e.linkTarget = ""
+			e.hardLink = cleanArchiveName(hdr.Linkname)
+			e.unsafe = e.unsafe || isUnsafeArchiveName(hdr.Linkname)
+		}
+		open := func() (io.ReadCloser, error) { return io.NopCloser(tr), nil }
+		if err := fn(e, open); err != nil {
+			return ignoreStopWalk(err)
+		}
+	}
+}
+
+// progressFile counts the bytes read from an archive file, and fails once ctx is canceled
+type progressFile struct {
+	ctx  context.Context
+	f    *os.File
+	read *int64
+}
+
+func (thiss *progressFile) Read(b []byte) (int, error) {
+	if err := thiss.ctx.Err(); err != nil {
+		return 0, err
+	}
+	n, err := thiss.f.Read(b)
+	atomic.AddInt64(thiss.read, int64(n))
+	return n, err
+}
+
+func (thiss *progressFile) ReadAt(b []byte, off int64) (int, error) {
+	if err := thiss.ctx.Err(); err != nil {
+		return 0, err
+	}
+	n, err := thiss.f.ReadAt(b, off)
+	atomic.AddInt64(thiss.read, int64(n))
+	return n, err
+}
+
+func ignoreStopWalk(err error) error {
+	if err == errStopWalk {
+		return nil
+	}
+	return err
+}
+
+func readZipLink(f *zip.File) string {
+	r, err := f.Open()
+	if err != nil {
+		return ""
+	}
+	defer r.Close()
+	target, _ := io.ReadAll(io.LimitReader(r, archiveMaxLinkSz))
+	return string(target)
+}
+
+// openArchive reads the index of an archive
+func openArchive(archivePath string) (*archive, error) {
+	a := &archive{
+		path:     archivePath,
+		entries:  map[string]archiveEntry{},
+		children: map[string][]archiveEntry{},
+	}
+	err := walkArchive(context.Background(), archivePath, nil, func(e archiveEntry, _ func() (io.ReadCloser, error)) error {
+		a.add(e)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot read %s: %w", filepath.Base(archivePath), err)
+	}
+	return a, nil
+}
+
+// add adds an entry and its parent folders to the index. An entry stored twice keeps the last one.
+// Hard links to files get the info of the file, which is stored before them
+func (thiss *archive) add(e archiveEntry) {
+	if e.name == "" {
+		return
+	}
+	if target, found := thiss.entries[e.hardLink]; e.hardLink != "" && found && target.info.Mode().IsRegular() {
+		e.info = renamedInfo{target.info, path.Base(e.name)}
+	}
+	if _, found := thiss.entries[e.name]; found {
+		thiss.entries[e.name] = e
+		siblings := thiss.children[parentArchiveDir(e.name)]
+		for ix := range siblings {
+			if siblings[ix].name == e.name {
+				siblings[ix] = e
+			}
+		}
+		return
+	}
+	parent := parentArchiveDir(e.name)
+	if _, found := thiss.entries[parent]; parent != "" && !found {
+		thiss.add(archiveEntry{name: parent, info: virtualDirInfo{path.Base(parent)}})
+	}
+	thiss.entries[e.name] = e
+	thiss.children[parent] = append(thiss.children[parent], e)
+}
+
+func parentArchiveDir(name string) string {
+	dir := path.Dir(name)
+	if dir == "." {
+		return ""
+	}
+	return dir
+}
+
+// isDir returns true if name is a folder of the archive ("" is the root)
+func (thiss *archive) isDir(name string) bool {
+	e, found := thiss.entries[name]
+	return name == "" || (found && e.info.IsDir())
+}
+
+// resolveLink returns the entry that a symlink entry points to, if it is inside the archive
+func (thiss *archive) resolveLink(e archiveEntry) (archiveEntry, bool) {
+	for ix := 0; ix < previewMaxLinks && e.linkTarget != ""; ix++ {
+		if path.IsAbs(e.linkTarget) {
+			return e, false
+		}
+		target := path.Join(parentArchiveDir(e.name), e.linkTarget)
+		next, found := thiss.entries[target]
+		if !found || strings.HasPrefix(target, "..") {
+			return e, false
+		}
+		e = next
+	}
+	return e, e.linkTarget == ""
+}
+
+// items returns the list items of a folder of the archive
+func (thiss *archive) items(dir string) []Item {
+	items := []Item{}
+	for _, e := range thiss.children[dir] {
+		info := e.info
+		name := path.Base(e.name)
+		if info.IsDir() {
+			name += "/"
+		}
+		item := Item{
+			name:        name,
+			fileInfo:    info,
+			archivePath: e.name,
+			details:     archiveDetails(info),
+		}
+		if e.linkTarget != "" {
+			item.linkTargetPath = e.linkTarget
+			target, ok := thiss.resolveLink(e)
+			item.linkTargetInfo = target.info
+			item.linkIsBroken = !ok
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// archiveDetails returns the details of an archive entry. Tar entries have owners, zip entries don't
+func archiveDetails(info os.FileInfo) ItemDetails {
+	details := ItemDetails{
+		Perm:     info.Mode().String(),
+		Username: "-",
+		Group:    "-",
+		Size:     humanize.Bytes(uint64(info.Size())),
+	}
+	if !info.ModTime().IsZero() {
+		details.Date = info.ModTime().Format("02 Jan 06 15:04")
+	}
+	if hdr, ok := info.Sys().(*tar.Header); ok {
+		details.Username, details.Group = hdr.Uname, hdr.Gname
+		if details.Username == "" {
+			details.Username = "( " + strconv.Itoa(hdr.Uid) + " )"
+		}
+		if details.Group == "" {
+			details.Group = "( " + strconv.Itoa(hdr.Gid) + " )"
+		}
+	}
+	if _, ok := info.(virtualDirInfo); ok {
+		details.Size = "-"
+	}
+	return details
+}
+
+// lsArchive lists the current folder of the open archive
+func (thiss *Model) lsArchive() error {
+	items := []Item{}
+	if config.ADD_ONE_DOT_FOLDER {
+		items = append(items, Item{name: "./"})
+	}
+	if config.ADD_TWO_DOT_FOLDER {
+		info := virtualDirInfo{".."}
+		items = append(items, Item{name: "../", fileInfo: info, details: archiveDetails(info)})
+	}
+	items = append(items, thiss.archive.items(thiss.archiveDir)...)
+	if config.LIST_FOLDERS_FIRST {
+		items = sortItemsFoldersFirst(items)
+	}
+	thiss.items = items
+	thiss.dirItems = items
+	thiss.refreshMarks()
+	return nil
+}
+
+// isFocusedOnArchive returns true if the focused item is an archive file of the current directory
+func (thiss *Model) isFocusedOnArchive() bool {
+	if len(thiss.items) == 0 || !isSelectable(thiss.CurrentItem()) || archiveFormat(thiss.CurrentItem().name) == "" {
+		return false
+	}
+	info, err := os.Stat(thiss.itemPath(thiss.CurrentItem()))
+	return err == nil && info.Mode().IsRegular()
+}
+
+// openFocusedArchive browses the focused archive
+func (thiss *Model) openFocusedArchive() {
+	a, err := openArchive(thiss.itemPath(thiss.CurrentItem()))
+	if err != nil {
+		thiss.err = err
+		return
+	}
+	thiss.archive = a
+	thiss.archiveDir = ""
+	thiss.changeMode(modeList)
+	thiss.refresh()
+	thiss.cursorIx = 0
+	thiss.rowOffset = 0
+}
+
+// archiveEnter enters the focused folder of the archive. It returns false for files, that cannot be
+// opened without extracting them
+func (thiss *Model) archiveEnter() bool {
+	e := thiss.archive.entries[thiss.CurrentItem().archivePath]
+	if target, ok := thiss.archive.resolveLink(e); ok {
+		e = target
+	}
+	if !e.info.IsDir() {
+		thiss.err = errors.New("files inside archives cannot be opened, extract them first")
+		return false
+	}
+	thiss.archiveDir = e.name
+	thiss.refresh()
+	thiss.cursorIx = 0
+	thiss.rowOffset = 0
+	return true
+}
+
+// archiveParent goes to the parent folder of the archive, or leaves it when on its root. The cursor
+// is set to the folder (or archive) that was left
+func (thiss *Model) archiveParent() {
+	name := path.Base(thiss.archiveDir)
+	if thiss.archiveDir == "" {
+		name = filepath.Base(thiss.archive.path)
+		thiss.archive = nil
+	} else {
+		thiss.archiveDir = parentArchiveDir(thiss.archiveDir)
+	}
+	thiss.refresh()
+	thiss.setCursorToName(name)
+}
+
+// displayPath returns the current path for the header. Inside archives, it is the archive path followed
+// by the path inside it
+func (thiss *Model) displayPath() string {
+	if thiss.archive == nil {
+		return thiss.path
+	}
+	return thiss.archive.path + term.Cyan(":/"+thiss.archiveDir, false)
+}
+
+// previewArchiveEntry returns the preview of an entry of the archive
+func previewArchiveEntry(a *archive, name string, width, height int) []string {
+	if a.isDir(name) {
+		lines := []string{}
+		for _, item := range a.items(name) {
+			if len(lines) == height {
+				break
+			}
+			lines = append(lines, addColorByFileType(truncate(item.name, width), item, false, nil))
+		}
+		if len(lines) == 0 {
+			lines = append(lines, term.Gray("(empty)", false))
+		}
+		return lines
+	}
+	e := a.entries[name]
+	if target, found := a.entries[e.hardLink]; e.hardLink != "" && found {
+		name, e = e.hardLink, target
+	}
+	if e.linkTarget != "" {
+		return []string{term.Cyan(truncate("-> "+e.linkTarget, width), false)}
+	}
+	if !e.info.Mode().IsRegular() {
+		return []string{term.Gray(truncate(e.info.Mode().String(), width), false)}
+	}
+	var data []byte
+	err := walkArchive(context.Background(), a.path, nil, func(entry archiveEntry, open func() (io.ReadCloser, error)) error {
+		if entry.name != name {
+			return nil
+		}
+		r, err := open()
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+		data, err = io.ReadAll(io.LimitReader(r, previewReadSz))
+		if err != nil {
+			return err
+		}
+		return errStopWalk
+	})
+	if err != nil {
+		return []string{term.Red(truncate(err.Error(), width), false)}
+	}
+	return previewData(name, data, 1, width, height)
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/gookit/color"
+	"github.com/klauspost/compress/zstd"
+)
+
+type testArchiveFile struct {
+	name, content, link string
+}
+
+var testArchiveFiles = []testArchiveFile{
+	{name: "docs/"},
+	{name: "docs/readme.md", content: "# Readme\n"},
+	{name: "src/main/app.go", content: "package main\n"}, // Folders without entries
+	{name: "../evil.txt", content: "evil"},
+	{name: "latest", link: "src/main"},
+}
+
+func writeTestTar(t *testing.T, w io.Writer) {
+	tw := tar.NewWriter(w)
+	for _, f := range testArchiveFiles {
+		hdr := &tar.Header{Name: f.name, Mode: 0644, Size: int64(len(f.content)), Typeflag: tar.TypeReg, Uname: "alice"}
+		if strings.HasSuffix(f.name, "/") {
+			hdr.Typeflag, hdr.Mode = tar.TypeDir, 0755
+		} else if f.link != "" {
+			hdr.Typeflag, hdr.Linkname = tar.TypeSymlink, f.link
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write([]byte(f.content))
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// writeTestArchive writes testArchiveFiles on an archive of the format given by the name extension
+func writeTestArchive(t *testing.T, path string) {
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	switch archiveFormat(path) {
+	case "zip":
+		zw := zip.NewWriter(f)
+		for _, af := range testArchiveFiles {
+			hdr := &zip.FileHeader{Name: af.name}
+			hdr.SetMode(0644)
+			if af.link != "" {
+				hdr.SetMode(os.ModeSymlink | 0777)
+				af.content = af.link
+			}
+			w, err := zw.CreateHeader(hdr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			w.Write([]byte(af.content))
+		}
+		if err := zw.Close(); err != nil {
+			t.Fatal(err)
+		}
+	case "tar":
+		writeTestTar(t, f)
+	case "tar.gz":
+		gz := gzip.NewWriter(f)
+		writeTestTar(t, gz)
+		gz.Close()
+	case "tar.zst":
+		zw, _ := zstd.NewWriter(f)
+		writeTestTar(t, zw)
+		zw.Close()
+	}
+}
+
+func TestArchiveFormat(t *testing.T) {
+	cases := map[string]string{
+		"a.zip": "zip", "a.TAR": "tar", "a.tar.gz": "tar.gz", "a.tgz": "tar.gz", "a.tar.zst": "tar.zst",
+		"a.gz": "", ".zip": "", "zip": "",
+	}
+	for name, format := range cases {
+		if archiveFormat(name) != format {
+			t.Error(name)
+		}
+	}
+}
+
+func TestOpenArchive(t *testing.T) {
+	tmp := t.TempDir()
+	for _, name := range []string{"a.zip", "a.tar", "a.tar.gz", "a.tar.zst"} {
+		path := filepath.Join(tmp, name)
+		writeTestArchive(t, path)
+		a, err := openArchive(path)
+		if err != nil {
+			t.Fatal(name, err)
+		}
+		names := []string{}
+		for _, it := range a.items("") {
+			names = append(names, it.name)
+		}
+		if strings.Join(names, " ") != "docs/ src/ evil.txt latest" {
+			t.Fatal(name, names)
+		}
+		if !a.isDir("src/main") || a.isDir("src/main/app.go") {
+			t.Fatal(name)
+		}
+		latest := a.items("")[3]
+		if latest.linkTargetPath != "src/main" || latest.linkIsBroken || !latest.linkTargetInfo.IsDir() {
+			t.Fatal(name, latest)
+		}
+	}
+}
+
+func TestBrowseArchive(t *testing.T) {
+	tmp := t.TempDir()
+	path := filepath.Join(tmp, "a.tar.gz")
+	writeTestArchive(t, path)
+	m := Model{path: tmp, width: 80, height: 20}
+	m.Init()
+	m.Update(tea.WindowSizeMsg{Width: 80, Height: 20})
+	m.setCursorToName("a.tar.gz")
+	m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if m.archive == nil || m.archiveDir != "" || !strings.Contains(color.ClearCode(m.View()), path+":/") {
+		t.Fatal(m.err)
+	}
+	if m.CurrentItem().details.Username != "-" { // ../
+		t.Fatal(m.CurrentItem().details)
+	}
+	m.setCursorToName("latest")
+	m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if m.archiveDir != "src/main" || len(m.items) != 2 || m.items[1].details.Username != "alice" {
+		t.Fatal(m.archiveDir, m.items)
+	}
+	// Typing filters inside the archive too
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("app")})
+	if len(m.items) != 1 || m.items[0].archivePath != "src/main/app.go" {
+		t.Fatal(m.items)
+	}
+	lines := previewArchiveEntry(m.archive, "src/main/app.go", 40, 10)
+	if len(lines) != 1 || color.ClearCode(lines[0]) != "package main" {
+		t.Fatalf("%q", lines)
+	}
+	// Files are not opened nor touched by file operations
+	m.Update(tea.KeyMsg{Type: tea.KeyDelete})
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("n"), Alt: true})
+	if m.err != errInsideArchive || m.mode != modeSearch {
+		t.Fatal(m.err, m.mode)
+	}
+	m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if m.err == nil || m.mode != modeSearch {
+		t.Fatal(m.mode)
+	}
+	m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	m.Update(tea.KeyMsg{Type: tea.KeyBackspace, Alt: true})
+	m.Update(tea.KeyMsg{Type: tea.KeyBackspace, Alt: true})
+	if m.archive == nil || m.archiveDir != "" || m.CurrentItem().name != "src/" {
+		t.Fatal(m.archiveDir, m.CurrentItem())
+	}
+	m.Update(tea.KeyMsg{Type: tea.KeyBackspace, Alt: true})
+	if m.archive != nil || m.path != tmp || m.CurrentItem().name != "a.tar.gz" {
+		t.Fatal(m.path, m.CurrentItem())
+	}
+}
+
+func TestCheckInsideDir(t *testing.T) {
+	tmp := t.TempDir()
+	os.Mkdir(filepath.Join(tmp, "root"), 0755)
+	os.Symlink(os.TempDir(), filepath.Join(tmp, "root", "escape"))
+	root := filepath.Join(tmp, "root")
+	if err := checkInsideDir(root, filepath.Join(root, "new", "dir")); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkInsideDir(root, filepath.Join(root, "escape", "dir")); err == nil {
+		t.Fatal("symlink escape not detected")
+	}
+}
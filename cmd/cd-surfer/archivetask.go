@@ -0,0 +1,457 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/dustin/go-humanize"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression and extraction run on background, one at a time, with their progress on the footer. Esc
+// on the list cancels them, removing what was created
+
+const archiveTaskTickInterval = 200 * time.Millisecond
+
+type archiveTask struct {
+	read   int64  // Bytes processed. Accessed atomically (first, to be 64-bit aligned)
+	total  int64  // Bytes to process, 0 while unknown. Accessed atomically
+	label  string // Like "Extracting a.zip"
+	cancel context.CancelFunc
+}
+
+type archiveTaskDoneMsg struct {
+	task    *archiveTask
+	created []string // Top level paths created by the task
+	err     error
+}
+
+type archiveTaskTickMsg struct {
+	task *archiveTask
+}
+
+// progress returns the task label and its progress, like "Extracting a.zip: 45% (12 MB / 26 MB)"
+func (thiss *archiveTask) progress() string {
+	read, total := atomic.LoadInt64(&thiss.read), atomic.LoadInt64(&thiss.total)
+	if total <= 0 {
+		return fmt.Sprintf("%s: %s", thiss.label, humanize.Bytes(uint64(read)))
+	}
+	return fmt.Sprintf("%s: %d%% (%s / %s)", thiss.label, min(int(read*100/total), 100),
+		humanize.Bytes(uint64(read)), humanize.Bytes(uint64(total)))
+}
+
+func archiveTaskTick(task *archiveTask) tea.Cmd {
+	return tea.Tick(archiveTaskTickInterval, func(time.Time) tea.Msg {
+		return archiveTaskTickMsg{task: task}
+	})
+}
+
+// startArchiveTask runs fn on background. fn returns the top level paths it created, which are removed
+// if the task is canceled
+func (thiss *Model) startArchiveTask(label string, fn func(ctx context.Context, task *archiveTask) ([]string, error)) tea.Cmd {
+	if thiss.task != nil {
+		thiss.err = errors.New("wait for the running task to finish, or cancel it")
+		return nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	task := &archiveTask{label: label, cancel: cancel}
+	thiss.task = task
+	return tea.Batch(func() tea.Msg {
+		created, err := fn(ctx, task)
+		if err != nil && ctx.Err() != nil {
+			for _, p := range created {
+				os.RemoveAll(p)
+			}
+			created, err = nil, errors.New(label+" canceled")
+		}
+		cancel()
+		return archiveTaskDoneMsg{task: task, created: created, err: err}
+	}, archiveTaskTick(task))
+}
+
+// finishArchiveTask shows the result of the task, with the cursor on the created item
+func (thiss *Model) finishArchiveTask(msg archiveTaskDoneMsg) {
+	if msg.task != thiss.task {
+		return
+	}
+	thiss.task = nil
+	thiss.err = msg.err
+	thiss.refresh()
+	if len(msg.created) > 0 && thiss.archive == nil && filepath.Dir(msg.created[0]) == filepath.Clean(thiss.path) {
+		thiss.setCursorToName(filepath.Base(msg.created[0]))
+	}
+}
+
+// compressTargets asks the name of the archive to create with the selected items (or the focused one)
+func (thiss *Model) compressTargets() {
+	targets := thiss.targetPaths()
+	if len(targets) == 0 {
+		return
+	}
+	name := filepath.Base(thiss.path)
+	if len(targets) == 1 {
+		name = filepath.Base(targets[0])
+	}
+	name = filepath.Base(uniqueDestPath(filepath.Join(thiss.path, name+".tar.gz")))
+	title := fmt.Sprintf("Compress %d item(s) to (.zip, .tar, .tar.gz or .tar.zst)", len(targets))
+	thiss.askInputCmd(title, name, func(input string) (tea.Cmd, error) {
+		dest := thiss.absInputPath(input)
+		if archiveFormat(dest) == "" {
+			return nil, errors.New("unknown archive format, use .zip, .tar, .tar.gz or .tar.zst")
+		}
+		return thiss.startArchiveTask("Compressing "+filepath.Base(dest), func(ctx context.Context, task *archiveTask) ([]string, error) {
+			return []string{dest}, createArchive(ctx, dest, targets, &task.total, &task.read)
+		}), nil
+	})
+}
+
+// extractFocused asks where to extract the focused archive or, inside archives, the focused entry. The
+// archive is extracted to a new folder by default ("." is the current directory)
+func (thiss *Model) extractFocused() {
+	if len(thiss.items) == 0 {
+		return
+	}
+	archivePath, prefix, initial := "", "", ""
+	switch {
+	case thiss.archive != nil && thiss.CurrentItem().archivePath != "":
+		archivePath, prefix, initial = thiss.archive.path, thiss.CurrentItem().archivePath, thiss.path
+	case thiss.archive == nil && thiss.isFocusedOnArchive():
+		archivePath = thiss.itemPath(thiss.CurrentItem())
+		initial = filepath.Base(uniqueDestPath(filepath.Join(thiss.path, archiveStem(filepath.Base(archivePath)))))
+	default:
+		return
+	}
+	name := filepath.Base(archivePath)
+	if prefix != "" {
+		name = filepath.Base(prefix)
+	}
+	thiss.askInputCmd("Extract "+name+" to", initial, func(input string) (tea.Cmd, error) {
+		dest := thiss.absInputPath(input)
+		_, statErr := os.Lstat(dest)
+		return thiss.startArchiveTask("Extracting "+name, func(ctx context.Context, task *archiveTask) ([]string, error) {
+			if info, err := os.Stat(archivePath); err == nil {
+				atomic.StoreInt64(&task.total, info.Size())
+			}
+			created, err := extractArchive(ctx, archivePath, prefix, dest, &task.read)
+			if statErr != nil { // The destination folder is new
+				created = []string{dest}
+			}
+			return created, err
+		}), nil
+	})
+}
+
+// absInputPath returns the absolute path of a path typed on the prompt, relative to the current
+// directory
+func (thiss *Model) absInputPath(input string) string {
+	if filepath.IsAbs(input) {
+		return filepath.Clean(input)
+	}
+	return filepath.Join(thiss.path, input)
+}
+
+// extractArchive extracts the entries beneath prefix ("" for all of them) into destDir. Folders are
+// extracted with their name: prefix "a/b" creates destDir/b. Top level names that are taken get a
+// " (n)" suffix, so nothing is overwritten. Unsafe entries (absolute or with "..") are skipped. Hard
+// links are linked to the extracted file they point to, which is read again from the archive if it was
+// not extracted. read is increased with the bytes read from the archive file. It returns the top level
+// paths created
+func extractArchive(ctx context.Context, archivePath, prefix, destDir string, read *int64) (created []string, err error) {
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		return nil, err
+	}
+	parent := parentArchiveDir(prefix)
+	tops := map[string]string{}           // Top level name -> its destination
+	extracted := map[string]string{}      // Name of the extracted files -> their destination
+	pendingLinks := map[string][]string{} // Name of the files not extracted -> destinations of their hard links
+	skipped := 0
+	err = walkArchive(ctx, archivePath, read, func(e archiveEntry, open func() (io.ReadCloser, error)) error {
+		if prefix != "" && e.name != prefix && !strings.HasPrefix(e.name, prefix+"/") {
+			return nil
+		}
+		if e.unsafe {
+			skipped++
+			return nil
+		}
+		rel := e.name
+		if parent != "" {
+			rel = strings.TrimPrefix(e.name, parent+"/")
+		}
+		top, rest, _ := strings.Cut(rel, "/")
+		topDest, found := tops[top]
+		if !found {
+			topDest = uniqueDestPath(filepath.Join(destDir, top))
+			tops[top] = topDest
+			created = append(created, topDest)
+		}
+		target := filepath.Join(topDest, filepath.FromSlash(rest))
+		if e.hardLink != "" {
+			if source, found := extracted[e.hardLink]; found {
+				return extractHardLink(destDir, source, target)
+			}
+			pendingLinks[e.hardLink] = append(pendingLinks[e.hardLink], target)
+			return nil
+		}
+		if err := extractEntry(destDir, target, e, open); err != nil {
+			return err
+		}
+		if !e.info.IsDir() {
+			extracted[e.name] = target
+		}
+		return nil
+	})
+	if err == nil && len(pendingLinks) > 0 {
+		// The files are outside of prefix: they are extracted to the first of their hard links
+		err = walkArchive(ctx, archivePath, read, func(e archiveEntry, open func() (io.ReadCloser, error)) error {
+			targets := pendingLinks[e.name]
+			if len(targets) == 0 || e.unsafe || e.hardLink != "" || e.info.IsDir() {
+				return nil
+			}
+			delete(pendingLinks, e.name)
+			if err := extractEntry(destDir, targets[0], e, open); err != nil {
+				return err
+			}
+			for _, target := range targets[1:] {
+				if err := extractHardLink(destDir, targets[0], target); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+	for _, targets := range pendingLinks {
+		skipped += len(targets)
+	}
+	if err == nil && skipped > 0 {
+		err = fmt.Errorf("%d unsafe entries (absolute, with ../ or hard links to missing files) were skipped", skipped)
+	}
+	return created, err
+}
+
+// extractHardLink links target to the extracted file source, or copies it if hard links are not
+// supported. Both must be inside root
+func extractHardLink(root, source, target string) error {
+	if err := checkInsideDir(root, filepath.Dir(source)); err != nil {
+		return err
+	}
+	if err := checkInsideDir(root, filepath.Dir(target)); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	if err := os.Link(source, target); err != nil {
+		return copyPath(source, target)
+	}
+	return nil
+}
+
+// extractEntry writes an archive entry to target, which must be inside root. Symlinks are created as
+// they are, but nothing is written through them
+func extractEntry(root, target string, e archiveEntry, open func() (io.ReadCloser, error)) error {
+	if err := checkInsideDir(root, filepath.Dir(target)); err != nil {
+		return err
+	}
+	mode := e.info.Mode()
+	switch {
+	case mode.IsDir():
+		return os.MkdirAll(target, mode.Perm()|0700)
+	case e.linkTarget != "":
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		return os.Symlink(e.linkTarget, target)
+	case !mode.IsRegular():
+		return nil // Devices, fifos...
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	r, err := open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	f, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode.Perm()|0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// checkInsideDir fails if dir, with the symlinks resolved, is not inside root. Only the existing part of
+// dir is checked
+func checkInsideDir(root, dir string) error {
+	resolvedRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return err
+	}
+	existing := dir
+	for {
+		if _, err := os.Lstat(existing); err == nil || existing == filepath.Dir(existing) {
+			break
+		}
+		existing = filepath.Dir(existing)
+	}
+	resolved, err := filepath.EvalSymlinks(existing)
+	if err != nil {
+		return err
+	}
+	if resolved != resolvedRoot && !isSubPath(resolvedRoot, resolved) {
+		return fmt.Errorf("%s is outside of %s", dir, root)
+	}
+	return nil
+}
+
+// createArchive compresses paths into archivePath, with the format given by its extension. The paths
+// are stored by their base names, with the folders contents and without following symlinks. total is
+// set to the size of the files to compress, and read is increased as they are read. The archive is
+// removed on failure
+func createArchive(ctx context.Context, archivePath string, paths []string, total, read *int64) (err error) {
+	format := archiveFormat(archivePath)
+	if format == "" {
+		return errors.New("unknown archive format, use .zip, .tar, .tar.gz or .tar.zst")
+	}
+	size := int64(0)
+	for _, p := range paths {
+		size += dirSize(p)
+	}
+	atomic.StoreInt64(total, size)
+
+	f, err := os.OpenFile(archivePath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(archivePath)
+		}
+	}()
+
+	// add writes an entry. link is the target of symlinks
+	var add func(name, fullPath string, info os.FileInfo, link string) error
+	var closeWriters func() error
+	copyContents := func(w io.Writer, fullPath string) error {
+		src, err := os.Open(fullPath)
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		_, err = io.Copy(w, &progressFile{ctx: ctx, f: src, read: read})
+		return err
+	}
+	if format == "zip" {
+		zw := zip.NewWriter(f)
+		add = func(name, fullPath string, info os.FileInfo, link string) error {
+			hdr, err := zip.FileInfoHeader(info)
+			if err != nil {
+				return err
+			}
+			hdr.Name = name
+			if info.IsDir() {
+				hdr.Name += "/"
+			} else {
+				hdr.Method = zip.Deflate
+			}
+			w, err := zw.CreateHeader(hdr)
+			switch {
+			case err != nil:
+				return err
+			case link != "":
+				_, err = io.WriteString(w, link) // Zip symlinks store the target as the content
+				return err
+			case info.Mode().IsRegular():
+				return copyContents(w, fullPath)
+			}
+			return nil
+		}
+		closeWriters = zw.Close
+	} else {
+		var w io.Writer = f
+		compressorClose := func() error { return nil }
+		switch format {
+		case "tar.gz":
+			gz := gzip.NewWriter(f)
+			w, compressorClose = gz, gz.Close
+		case "tar.zst":
+			zw, err := zstd.NewWriter(f)
+			if err != nil {
+				return err
+			}
+			w, compressorClose = zw, zw.Close
+		}
+		tw := tar.NewWriter(w)
+		add = func(name, fullPath string, info os.FileInfo, link string) error {
+			hdr, err := tar.FileInfoHeader(info, link)
+			if err != nil {
+				return err
+			}
+			hdr.Name = name
+			if info.IsDir() {
+				hdr.Name += "/"
+			}
+			if err := tw.WriteHeader(hdr); err != nil {
+				return err
+			}
+			if info.Mode().IsRegular() {
+				return copyContents(tw, fullPath)
+			}
+			return nil
+		}
+		closeWriters = func() error {
+			if err := tw.Close(); err != nil {
+				compressorClose()
+				return err
+			}
+			return compressorClose()
+		}
+	}
+
+	for _, p := range paths {
+		base := filepath.Dir(p)
+		err := filepath.Walk(p, func(fullPath string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if fullPath == archivePath {
+				return nil
+			}
+			link := ""
+			if info.Mode()&os.ModeSymlink != 0 {
+				if link, err = os.Readlink(fullPath); err != nil {
+					return err
+				}
+			} else if !info.IsDir() && !info.Mode().IsRegular() {
+				return nil // Devices, sockets...
+			}
+			rel, err := filepath.Rel(base, fullPath)
+			if err != nil {
+				return err
+			}
+			return add(filepath.ToSlash(rel), fullPath, info, link)
+		})
+		if err != nil {
+			closeWriters()
+			return err
+		}
+	}
+	return closeWriters()
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"archive/tar"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestExtractArchive(t *testing.T) {
+	tmp := t.TempDir()
+	path := filepath.Join(tmp, "a.zip")
+	writeTestArchive(t, path)
+	dest := filepath.Join(tmp, "out")
+	var read int64
+	created, err := extractArchive(context.Background(), path, "", dest, &read)
+	if err == nil || !strings.Contains(err.Error(), "1 unsafe") || read == 0 {
+		t.Fatal(err, read)
+	}
+	if len(created) != 3 {
+		t.Fatal(created)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dest, "src", "main", "app.go")); string(data) != "package main\n" {
+		t.Fatal(string(data))
+	}
+	if target, _ := os.Readlink(filepath.Join(dest, "latest")); target != "src/main" {
+		t.Fatal(target)
+	}
+	for _, p := range []string{filepath.Join(tmp, "evil.txt"), filepath.Join(dest, "evil.txt")} {
+		if _, err := os.Lstat(p); err == nil {
+			t.Fatal("unsafe entry extracted", p)
+		}
+	}
+
+	// Entries are extracted with their name, and taken names get a suffix
+	created, err = extractArchive(context.Background(), path, "src/main", dest, &read)
+	if err != nil || len(created) != 1 || created[0] != filepath.Join(dest, "main") {
+		t.Fatal(created, err)
+	}
+	created, _ = extractArchive(context.Background(), path, "docs/readme.md", dest, &read)
+	created, _ = extractArchive(context.Background(), path, "docs/readme.md", dest, &read)
+	if len(created) != 1 || created[0] != filepath.Join(dest, "readme (1).md") {
+		t.Fatal(created)
+	}
+}
+
+func TestExtractHardLinks(t *testing.T) {
+	tmp := t.TempDir()
+	path := filepath.Join(tmp, "a.tar")
+	f, _ := os.Create(path)
+	tw := tar.NewWriter(f)
+	tw.WriteHeader(&tar.Header{Name: "bin/tool", Mode: 0755, Size: 5, Typeflag: tar.TypeReg})
+	tw.Write([]byte("hello"))
+	tw.WriteHeader(&tar.Header{Name: "bin/tool-alias", Linkname: "bin/tool", Mode: 0755, Typeflag: tar.TypeLink})
+	tw.WriteHeader(&tar.Header{Name: "lib/tool", Linkname: "bin/tool", Mode: 0755, Typeflag: tar.TypeLink})
+	tw.WriteHeader(&tar.Header{Name: "lib/evil", Linkname: "../etc/passwd", Mode: 0644, Typeflag: tar.TypeLink})
+	tw.Close()
+	f.Close()
+
+	a, err := openArchive(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.entries["bin/tool-alias"].info.Size() != 5 || a.entries["bin/tool-alias"].info.Name() != "tool-alias" {
+		t.Fatal(a.entries["bin/tool-alias"].info)
+	}
+	if lines := previewArchiveEntry(a, "lib/tool", 80, 10); len(lines) != 1 || !strings.Contains(lines[0], "hello") {
+		t.Fatal(lines)
+	}
+
+	dest := filepath.Join(tmp, "out")
+	_, err = extractArchive(context.Background(), path, "", dest, new(int64))
+	if err == nil || !strings.Contains(err.Error(), "1 unsafe") {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"bin/tool-alias", "lib/tool"} {
+		if data, _ := os.ReadFile(filepath.Join(dest, name)); string(data) != "hello" {
+			t.Fatal(name, string(data))
+		}
+	}
+	if _, err := os.Lstat(filepath.Join(dest, "lib", "evil")); err == nil {
+		t.Fatal("unsafe hard link extracted")
+	}
+
+	// The file linked to is outside of the extracted folder
+	if _, err := extractArchive(context.Background(), path, "lib/tool", dest, new(int64)); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dest, "tool")); string(data) != "hello" {
+		t.Fatal(string(data))
+	}
+}
+
+func TestCreateArchive(t *testing.T) {
+	tmp := t.TempDir()
+	src := filepath.Join(tmp, "src")
+	os.MkdirAll(filepath.Join(src, "sub"), 0755)
+	os.WriteFile(filepath.Join(src, "sub", "a.txt"), []byte("hello"), 0644)
+	os.Symlink("sub/a.txt", filepath.Join(src, "link"))
+	os.WriteFile(filepath.Join(tmp, "b.txt"), []byte("world!"), 0600)
+
+	for _, name := range []string{"out.zip", "out.tar", "out.tar.gz", "out.tar.zst"} {
+		path := filepath.Join(tmp, name)
+		var total, read int64
+		err := createArchive(context.Background(), path, []string{src, filepath.Join(tmp, "b.txt")}, &total, &read)
+		if err != nil || total != 11 || read != 11 {
+			t.Fatal(name, err, total, read)
+		}
+		a, err := openArchive(path)
+		if err != nil {
+			t.Fatal(name, err)
+		}
+		if !a.isDir("src/sub") || a.entries["src/link"].linkTarget != "sub/a.txt" ||
+			a.entries["b.txt"].info.Mode().Perm() != 0600 {
+			t.Fatal(name, a.entries)
+		}
+		dest := filepath.Join(tmp, "x"+name)
+		if _, err := extractArchive(context.Background(), path, "", dest, &read); err != nil {
+			t.Fatal(name, err)
+		}
+		if data, _ := os.ReadFile(filepath.Join(dest, "src", "link")); string(data) != "hello" {
+			t.Fatal(name, string(data))
+		}
+	}
+	if err := createArchive(context.Background(), filepath.Join(tmp, "out.zip"), []string{src}, new(int64), new(int64)); err == nil {
+		t.Fatal("existing archive overwritten")
+	}
+	if err := createArchive(context.Background(), filepath.Join(tmp, "out.rar"), []string{src}, new(int64), new(int64)); err == nil {
+		t.Fatal("unknown format accepted")
+	}
+}
+
+func TestArchiveTaskCancel(t *testing.T) {
+	tmp := t.TempDir()
+	os.WriteFile(filepath.Join(tmp, "big"), make([]byte, 1<<20), 0644)
+	m := Model{path: tmp, width: 80, height: 20}
+	m.Init()
+	m.Update(tea.WindowSizeMsg{Width: 80, Height: 20})
+	m.setCursorToName("big")
+	started := make(chan bool)
+	cmd := m.startArchiveTask("Compressing big.zip", func(ctx context.Context, task *archiveTask) ([]string, error) {
+		dest := filepath.Join(tmp, "big.zip")
+		started <- true
+		<-ctx.Done()
+		return []string{dest}, createArchive(ctx, dest, []string{filepath.Join(tmp, "big")}, &task.total, &task.read)
+	})
+	if m.startArchiveTask("other", nil) != nil || m.err == nil {
+		t.Fatal("two tasks at once")
+	}
+	done := make(chan tea.Msg)
+	go func() {
+		for _, c := range cmd().(tea.BatchMsg) {
+			go func(c tea.Cmd) {
+				if msg, ok := c().(archiveTaskDoneMsg); ok {
+					done <- msg
+				}
+			}(c)
+		}
+	}()
+	<-started
+	if !strings.Contains(m.View(), "Compressing big.zip") {
+		t.Fatal(m.View())
+	}
+	// Esc leaves the other views without canceling
+	m.changeMode(modeSearch)
+	m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	select {
+	case <-done:
+		t.Fatal("canceled when leaving the search")
+	case <-time.After(100 * time.Millisecond):
+	}
+	if m.mode != modeList {
+		t.Fatal(m.mode)
+	}
+	m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	select {
+	case msg := <-done:
+		m.Update(msg)
+	case <-time.After(5 * time.Second):
+		t.Fatal("not canceled")
+	}
+	if m.task != nil || m.err == nil || !strings.Contains(m.err.Error(), "canceled") {
+		t.Fatal(m.err)
+	}
+	if _, err := os.Lstat(filepath.Join(tmp, "big.zip")); err == nil {
+		t.Fatal("canceled archive not removed")
+	}
+}
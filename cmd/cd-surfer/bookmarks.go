@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/BurntSushi/toml"
+	"github.com/andriykrefer/cdsurfer/config"
+)
+
+// Bookmarks are folders saved under a key (a letter or digit), like vim marks. They are stored on
+// $XDG_DATA_HOME/cd-surfer/bookmarks.toml
+
+type bookmark struct {
+	Key  string `toml:"key"`
+	Name string `toml:"name"`
+	Path string `toml:"path"`
+}
+
+type bookmarksFile struct {
+	Bookmark []bookmark `toml:"bookmark"`
+}
+
+func bookmarksPath() string {
+	return filepath.Join(config.DataDir(), "bookmarks.toml")
+}
+
+// isBookmarkKey returns true for the keys that bookmarks can be saved under
+func isBookmarkKey(k string) bool {
+	r, size := utf8.DecodeRuneInString(k)
+	return size == len(k) && (unicode.IsLetter(r) || unicode.IsDigit(r))
+}
+
+// loadBookmarks reads the bookmarks, sorted by key. A missing file has no bookmarks
+func loadBookmarks() ([]bookmark, error) {
+	var file bookmarksFile
+	if _, err := toml.DecodeFile(bookmarksPath(), &file); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("%s: %w", bookmarksPath(), err)
+	}
+	sort.SliceStable(file.Bookmark, func(i, j int) bool { return file.Bookmark[i].Key < file.Bookmark[j].Key })
+	return file.Bookmark, nil
+}
+
+// saveBookmarks writes the bookmarks file. It is written to a temporary file first, so it is never left
+// half written
+func saveBookmarks(bookmarks []bookmark) error {
+	if err := os.MkdirAll(config.DataDir(), 0700); err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(bookmarksFile{Bookmark: bookmarks}); err != nil {
+		return err
+	}
+	tmp := bookmarksPath() + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, bookmarksPath())
+}
+
+// findBookmark returns the index of the bookmark with the given key, or -1
+func findBookmark(bookmarks []bookmark, k string) int {
+	for ix, b := range bookmarks {
+		if b.Key == k {
+			return ix
+		}
+	}
+	return -1
+}
+
+// setBookmark saves path under the key k, replacing the bookmark that was there. It is named after the
+// folder
+func setBookmark(k, path string) error {
+	bookmarks, err := loadBookmarks()
+	if err != nil {
+		return err
+	}
+	b := bookmark{Key: k, Name: filepath.Base(path), Path: path}
+	if ix := findBookmark(bookmarks, k); ix >= 0 {
+		bookmarks[ix] = b
+	} else {
+		bookmarks = append(bookmarks, b)
+	}
+	return saveBookmarks(bookmarks)
+}
+
+func renameBookmark(k, name string) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return errors.New("the name cannot be empty")
+	}
+	bookmarks, err := loadBookmarks()
+	if err != nil {
+		return err
+	}
+	ix := findBookmark(bookmarks, k)
+	if ix < 0 {
+		return fmt.Errorf("no bookmark on '%s'", k)
+	}
+	bookmarks[ix].Name = name
+	return saveBookmarks(bookmarks)
+}
+
+func deleteBookmark(k string) error {
+	bookmarks, err := loadBookmarks()
+	if err != nil {
+		return err
+	}
+	ix := findBookmark(bookmarks, k)
+	if ix < 0 {
+		return nil
+	}
+	return saveBookmarks(append(bookmarks[:ix], bookmarks[ix+1:]...))
+}
+
+// askKey waits for the next key, showing title on the header. action runs if it is a bookmark key.
+// Other keys cancel it
+func (thiss *Model) askKey(title string, action func(k string) error) {
+	thiss.keyPromptTitle = title
+	thiss.keyPromptAction = action
+}
+
+// markCurrentDir asks the key to bookmark the current directory under
+func (thiss *Model) markCurrentDir() {
+	path := filepath.Clean(thiss.path)
+	thiss.askKey("Bookmark "+path+" as (letter or digit)", func(k string) error {
+		return setBookmark(k, path)
+	})
+}
+
+// jumpToBookmark goes to the bookmark saved under the key k
+func (thiss *Model) jumpToBookmark(k string) error {
+	bookmarks, err := loadBookmarks()
+	if err != nil {
+		return err
+	}
+	ix := findBookmark(bookmarks, k)
+	if ix < 0 {
+		return fmt.Errorf("no bookmark on '%s'", k)
+	}
+	thiss.changeMode(modeList)
+	thiss.goToPath(bookmarks[ix].Path)
+	return thiss.err
+}
+
+// lsBookmarks fills the view with the bookmarks, named like "a  project  /home/me/project". Bookmarks
+// of missing folders are flagged
+func (thiss *Model) lsBookmarks() {
+	bookmarks, err := loadBookmarks()
+	if err != nil {
+		thiss.err = err
+	}
+	thiss.bookmarks = bookmarks
+	nameSz := 0
+	for _, b := range bookmarks {
+		nameSz = max(nameSz, utf8.RuneCountInString(b.Name))
+	}
+	items := []Item{}
+	for _, b := range bookmarks {
+		name := b.Key + "  " + b.Name + strings.Repeat(" ", nameSz-utf8.RuneCountInString(b.Name)) + "  " + b.Path
+		info, err := os.Stat(b.Path)
+		if err != nil {
+			info = virtualDirInfo{filepath.Base(b.Path)}
+			name += " (missing)"
+		}
+		items = append(items, pathItem(name, b.Path, info, err))
+	}
+	thiss.items = items
+}
+
+// focusedBookmark returns the focused bookmark on the bookmarks view
+func (thiss *Model) focusedBookmark() (bookmark, bool) {
+	if thiss.cursorIx >= len(thiss.bookmarks) || len(thiss.items) == 0 {
+		return bookmark{}, false
+	}
+	return thiss.bookmarks[thiss.cursorIx], true
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestBookmarksFile(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", filepath.Join(tmp, "data"))
+	if bookmarks, err := loadBookmarks(); err != nil || len(bookmarks) != 0 {
+		t.Fatal(bookmarks, err)
+	}
+	setBookmark("w", "/tmp/work")
+	setBookmark("a", "/tmp/a")
+	setBookmark("w", "/tmp/work2") // Replaces it
+	if err := renameBookmark("a", " first "); err != nil {
+		t.Fatal(err)
+	}
+	if renameBookmark("a", "") == nil || renameBookmark("z", "name") == nil {
+		t.Fail()
+	}
+	bookmarks, err := loadBookmarks()
+	if err != nil || len(bookmarks) != 2 ||
+		bookmarks[0] != (bookmark{"a", "first", "/tmp/a"}) || bookmarks[1] != (bookmark{"w", "work2", "/tmp/work2"}) {
+		t.Fatal(bookmarks, err)
+	}
+	deleteBookmark("a")
+	bookmarks, _ = loadBookmarks()
+	if len(bookmarks) != 1 || bookmarks[0].Key != "w" {
+		t.Fatal(bookmarks)
+	}
+	if isBookmarkKey("ab") || isBookmarkKey("'") || !isBookmarkKey("7") {
+		t.Fail()
+	}
+}
+
+func TestMarkAndJump(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", filepath.Join(tmp, "data"))
+	os.Mkdir(filepath.Join(tmp, "project"), 0755)
+	os.Mkdir(filepath.Join(tmp, "gone"), 0755)
+	m := Model{path: filepath.Join(tmp, "project")}
+	m.Init()
+	m.Update(tea.WindowSizeMsg{Width: 80, Height: 20})
+
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("b"), Alt: true})
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("p")})
+	m.goToPath(filepath.Join(tmp, "gone"))
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("b"), Alt: true})
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("g")})
+	// Other keys cancel the key prompt
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("b"), Alt: true})
+	m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if m.keyPromptAction != nil || m.mode != modeList {
+		t.Fatal(m.mode)
+	}
+	m.goToPath(tmp)
+	os.Remove(filepath.Join(tmp, "gone"))
+
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("'"), Alt: true})
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("p")})
+	if m.path != filepath.Join(tmp, "project") || m.err != nil {
+		t.Fatal(m.path, m.err)
+	}
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("'"), Alt: true})
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("x")})
+	if m.err == nil {
+		t.Fail()
+	}
+
+	// Bookmarks view: missing folders are flagged, F2 renames and Delete removes
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("B"), Alt: true})
+	if m.mode != modeBookmarks || len(m.items) != 2 || !strings.HasSuffix(m.items[0].name, "(missing)") ||
+		m.items[0].infoErr == nil || m.items[1].infoErr != nil {
+		t.Fatal(m.mode, m.items)
+	}
+	m.Update(tea.KeyMsg{Type: tea.KeyF2})
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("2")})
+	m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if m.mode != modeBookmarks || !strings.HasPrefix(m.items[0].name, "g  gone2") {
+		t.Fatal(m.mode, m.items[0].name, m.err)
+	}
+	m.Update(tea.KeyMsg{Type: tea.KeyDelete})
+	if len(m.items) != 1 || m.bookmarks[0].Key != "p" {
+		t.Fatal(m.items)
+	}
+	m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("B"), Alt: true})
+	m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if m.mode != modeList || m.path != filepath.Join(tmp, "project") {
+		t.Fatal(m.mode, m.path)
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// itemPath returns the absolute path of an item of the current directory
+func (thiss *Model) itemPath(item Item) string {
+	return filepath.Join(thiss.path, item.fileInfo.Name())
+}
+
+// toggleClipboard moves the selection to the clipboard or, when nothing is selected, adds or removes
+// the focused item from it. Switching between copy and cut starts a new clipboard
+func (thiss *Model) toggleClipboard(op clipboardOpEnum) {
+	if len(thiss.selection) > 0 {
+		thiss.clipboard = thiss.selectedPaths()
+		thiss.clipboardOp = op
+		thiss.clearSelection()
+		return
+	}
+	if len(thiss.items) == 0 || !isSelectable(thiss.CurrentItem()) {
+		return
+	}
+	if op != thiss.clipboardOp {
+		thiss.clipboard = nil
+		thiss.clipboardOp = op
+	}
+	path := thiss.itemPath(thiss.CurrentItem())
+	removed := false
+	for ix, p := range thiss.clipboard {
+		if p == path {
+			thiss.clipboard = append(thiss.clipboard[:ix], thiss.clipboard[ix+1:]...)
+			removed = true
+			break
+		}
+	}
+	if !removed {
+		thiss.clipboard = append(thiss.clipboard, path)
+	}
+	thiss.refreshMarks()
+}
+
+// paste copies or moves the clipboard items into the current directory. Name clashes get a
+// " (n)" suffix. The cursor is set to the first pasted item. On cut, the moved items leave the clipboard;
+// the ones that were not moved, because of an error, are kept
+func (thiss *Model) paste() {
+	if len(thiss.clipboard) == 0 {
+		return
+	}
+	firstName := ""
+	notMoved := []string{}
+	for ix, src := range thiss.clipboard {
+		if thiss.clipboardOp == clipboardOpCut && filepath.Dir(src) == filepath.Clean(thiss.path) {
+			continue // Moving to the same folder does nothing
+		}
+		if isSubPath(src, thiss.path) {
+			thiss.err = fmt.Errorf("cannot paste %s into itself", src)
+			notMoved = thiss.clipboard[ix:]
+			break
+		}
+		dst := uniqueDestPath(filepath.Join(thiss.path, filepath.Base(src)))
+		var err error
+		if thiss.clipboardOp == clipboardOpCut {
+			err = movePath(src, dst)
+		} else {
+			err = copyPath(src, dst)
+		}
+		if err != nil {
+			thiss.err = err
+			notMoved = thiss.clipboard[ix:]
+			break
+		}
+		if firstName == "" {
+			firstName = filepath.Base(dst)
+		}
+	}
+	if thiss.clipboardOp == clipboardOpCut {
+		thiss.clipboard = nil
+		if len(notMoved) > 0 {
+			thiss.clipboard = append([]string{}, notMoved...)
+		}
+	}
+	thiss.refresh()
+	thiss.setCursorToName(firstName)
+}
+
+// setCursorToName focus the item with the given name on the current view, if present
+func (thiss *Model) setCursorToName(name string) {
+	for ix, it := range thiss.items {
+		if it.fileInfo != nil && it.fileInfo.Name() == name {
+			thiss.cursorIx = ix
+			thiss.setOffsetToMiddleScreen()
+			return
+		}
+	}
+}
+
+// refreshMarks highlights the items that are selected or on the clipboard
+func (thiss *Model) refreshMarks() {
+	inClipboard := map[string]bool{}
+	for _, p := range thiss.clipboard {
+		inClipboard[p] = true
+	}
+	for _, list := range [][]Item{thiss.dirItems, thiss.filteredItems} {
+		for ix := range list {
+			if !isSelectable(list[ix]) {
+				continue
+			}
+			path := thiss.itemPath(list[ix])
+			_, list[ix].isSelected = thiss.selection[path]
+			list[ix].isInClipboard = inClipboard[path]
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/andriykrefer/cdsurfer/config"
+)
+
+// validateNewPath checks a path typed by the user, relative to the current directory. Nested paths
+// (a/b/c) are allowed, but they must stay inside the current directory
+func validateNewPath(relPath string) (string, error) {
+	clean := filepath.Clean(relPath)
+	if relPath == "" || filepath.IsAbs(relPath) || clean == "." || clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", fmt.Errorf("invalid path %q: it must be relative to the current folder", relPath)
+	}
+	return clean, nil
+}
+
+// firstSegment returns the first element of a relative path (a for a/b/c)
+func firstSegment(relPath string) string {
+	return strings.Split(relPath, "/")[0]
+}
+
+// createFolder creates the folder and its parents (like mkdir -p), and focus it
+func (thiss *Model) createFolder(relPath string) error {
+	relPath, err := validateNewPath(relPath)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(thiss.path, relPath)
+	if _, err := os.Lstat(path); err == nil {
+		return fmt.Errorf("%s already exists", relPath)
+	}
+	err = os.MkdirAll(path, 0755)
+	thiss.refresh()
+	thiss.setCursorToName(firstSegment(relPath))
+	return err
+}
+
+// createFile creates an empty file (and its parent folders), and focus it. If there is a template
+// for it, it is used as the initial content. If it fails, the parent folders it created are removed
+func (thiss *Model) createFile(relPath string) (err error) {
+	relPath, err = validateNewPath(relPath)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(thiss.path, relPath)
+	firstCreated := firstMissingDir(filepath.Dir(path))
+	defer func() {
+		if err != nil && firstCreated != "" {
+			removeEmptyDirs(filepath.Dir(path), firstCreated)
+		}
+	}()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	content := []byte{}
+	perm := os.FileMode(0644)
+	if template := findTemplate(filepath.Base(relPath)); template != "" {
+		info, err := os.Stat(template)
+		if err != nil {
+			return err
+		}
+		content, err = os.ReadFile(template)
+		if err != nil {
+			return err
+		}
+		perm = info.Mode().Perm()
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if errors.Is(err, os.ErrExist) {
+		return fmt.Errorf("%s already exists", relPath)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(content)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return err
+	}
+	thiss.refresh()
+	thiss.setCursorToName(firstSegment(relPath))
+	return nil
+}
+
+// firstMissingDir returns the topmost folder of dir that does not exist, or "" if dir exists
+func firstMissingDir(dir string) string {
+	missing := ""
+	for ; dir != filepath.Dir(dir); dir = filepath.Dir(dir) {
+		if _, err := os.Lstat(dir); err == nil {
+			break
+		}
+		missing = dir
+	}
+	return missing
+}
+
+// removeEmptyDirs removes dir and its parents up to top, while they are empty
+func removeEmptyDirs(dir, top string) {
+	for {
+		if os.Remove(dir) != nil || dir == top || dir == filepath.Dir(dir) {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+// findTemplate returns the template for a new file named name: a file with the same name in the
+// templates dir (e.g. Makefile), or else one named "template" with the same extension (e.g.
+// template.sh). Returns "" if there is none
+func findTemplate(name string) string {
+	candidates := []string{filepath.Join(config.TemplatesDir(), name)}
+	if ext := filepath.Ext(name); ext != "" && ext != name {
+		candidates = append(candidates, filepath.Join(config.TemplatesDir(), "template"+ext))
+	}
+	for _, c := range candidates {
+		info, err := os.Stat(c)
+		if err == nil && info.Mode().IsRegular() {
+			return c
+		}
+	}
+	return ""
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCreateFolderAndFile(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(tmp, "config"))
+	os.MkdirAll(filepath.Join(tmp, "config", "cd-surfer", "templates"), 0755)
+	os.WriteFile(filepath.Join(tmp, "config", "cd-surfer", "templates", "template.sh"), []byte("#!/bin/sh\n"), 0755)
+	dir := filepath.Join(tmp, "dir")
+	os.Mkdir(dir, 0755)
+	m := Model{path: dir, width: 80, height: 20}
+
+	if err := m.createFolder("a/b/c"); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(filepath.Join(dir, "a", "b", "c")); err != nil || !info.IsDir() {
+		t.Fail()
+	}
+	if m.CurrentItem().name != "a/" {
+		t.Fail()
+	}
+	if err := m.createFolder("a/b"); err == nil {
+		t.Fail()
+	}
+	if err := m.createFolder("../escape"); err == nil {
+		t.Fail()
+	}
+
+	if err := m.createFile("run.sh"); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, "run.sh"))
+	info, _ := os.Stat(filepath.Join(dir, "run.sh"))
+	if string(data) != "#!/bin/sh\n" || info.Mode().Perm() != 0755 {
+		t.Fail()
+	}
+	if m.CurrentItem().name != "run.sh" {
+		t.Fail()
+	}
+	if err := m.createFile("run.sh"); err == nil {
+		t.Fail()
+	}
+
+	// The parent folders are removed if the file cannot be created
+	if err := m.createFile("new/dirs/" + strings.Repeat("x", 300)); err == nil {
+		t.Fail()
+	}
+	if _, err := os.Lstat(filepath.Join(dir, "new")); err == nil {
+		t.Fail()
+	}
+	if err := m.createFile("a/b/" + strings.Repeat("x", 300)); err == nil {
+		t.Fail()
+	}
+	if _, err := os.Lstat(filepath.Join(dir, "a", "b", "c")); err != nil {
+		t.Fail()
+	}
+}
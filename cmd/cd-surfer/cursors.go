@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/andriykrefer/cdsurfer/config"
+)
+
+// Cursor memory: the focused item and the scroll of each visited directory, restored when going back to
+// it. It is kept for the session and, with save_cursors, saved on $XDG_DATA_HOME/cd-surfer/cursors.toml
+
+// cursorsMaxSz is the number of directories saved. The least recently visited are forgotten
+const cursorsMaxSz = 1000
+
+type dirCursor struct {
+	Name      string `toml:"name"` // Focused item
+	Ix        int    `toml:"ix"`   // Used when the focused item is gone
+	RowOffset int    `toml:"row_offset"`
+	Visited   int64  `toml:"visited"` // Unix time
+}
+
+type cursorsFile struct {
+	Cursor map[string]dirCursor `toml:"cursor"` // Path -> its cursor
+}
+
+func cursorsPath() string {
+	return filepath.Join(config.DataDir(), "cursors.toml")
+}
+
+// loadCursors reads the saved cursors. A missing file has no cursors
+func loadCursors() (map[string]dirCursor, error) {
+	var file cursorsFile
+	if _, err := toml.DecodeFile(cursorsPath(), &file); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return map[string]dirCursor{}, nil
+		}
+		return map[string]dirCursor{}, fmt.Errorf("%s: %w", cursorsPath(), err)
+	}
+	if file.Cursor == nil {
+		file.Cursor = map[string]dirCursor{}
+	}
+	return file.Cursor, nil
+}
+
+// saveCursors writes the cursors of the most recently visited directories
+func saveCursors(cursors map[string]dirCursor) error {
+	paths := []string{}
+	for path := range cursors {
+		paths = append(paths, path)
+	}
+	sort.Slice(paths, func(i, j int) bool { return cursors[paths[i]].Visited > cursors[paths[j]].Visited })
+	file := cursorsFile{Cursor: map[string]dirCursor{}}
+	for _, path := range paths[:min(len(paths), cursorsMaxSz)] {
+		file.Cursor[path] = cursors[path]
+	}
+	if err := os.MkdirAll(config.DataDir(), 0700); err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(file); err != nil {
+		return err
+	}
+	tmp := cursorsPath() + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, cursorsPath())
+}
+
+// rememberCursor saves the focused item and the scroll of the current directory. Only the list and the
+// search have the directory items, other views remember it when they are opened
+func (thiss *Model) rememberCursor() {
+	if thiss.archive != nil || (thiss.mode != modeList && thiss.mode != modeSearch) {
+		return
+	}
+	if thiss.cursors == nil {
+		thiss.cursors = map[string]dirCursor{}
+	}
+	c := dirCursor{Ix: thiss.cursorIx, RowOffset: thiss.rowOffset, Visited: time.Now().Unix()}
+	if thiss.cursorIx < len(thiss.items) && thiss.CurrentItem().fileInfo != nil {
+		c.Name = thiss.CurrentItem().fileInfo.Name()
+	}
+	thiss.cursors[filepath.Clean(thiss.path)] = c
+}
+
+// restoreCursor focuses the item that was focused when the current directory was left, or the item at
+// its position if it is gone. Directories that were not visited start on the first item
+func (thiss *Model) restoreCursor() {
+	c, found := thiss.cursors[filepath.Clean(thiss.path)]
+	if !found || thiss.archive != nil {
+		thiss.cursorIx = 0
+		thiss.rowOffset = 0
+		thiss.calculateColsAndRows()
+		return
+	}
+	thiss.setCursor(c.Name, c.Ix, c.RowOffset)
+}
+
+// setCursor focuses the item named name (or the one at ix, if there is none), scrolled to rowOffset if
+// the item is displayed with it
+func (thiss *Model) setCursor(name string, ix, rowOffset int) {
+	thiss.calculateColsAndRows()
+	thiss.cursorIx = ix
+	for itemIx, it := range thiss.items {
+		if it.fileInfo != nil && it.fileInfo.Name() == name {
+			thiss.cursorIx = itemIx
+			break
+		}
+	}
+	thiss.rowOffset = 0
+	thiss.addRowOffset(rowOffset)
+	thiss.fixCursor()
+}
+
+// initCursors loads the saved cursors, with save_cursors
+func (thiss *Model) initCursors() {
+	thiss.cursors = map[string]dirCursor{}
+	if !config.SAVE_CURSORS {
+		return
+	}
+	cursors, err := loadCursors()
+	if err != nil {
+		thiss.err = err
+	}
+	thiss.cursors = cursors
+}
+
+// saveCursors saves the cursors on exit, with save_cursors
+func (thiss *Model) saveCursors() error {
+	if !config.SAVE_CURSORS {
+		return nil
+	}
+	thiss.rememberCursor()
+	saved, err := loadCursors() // Other instances may have saved theirs
+	if err != nil {
+		return err
+	}
+	for path, c := range thiss.cursors {
+		if c.Visited >= saved[path].Visited {
+			saved[path] = c
+		}
+	}
+	return saveCursors(saved)
+}
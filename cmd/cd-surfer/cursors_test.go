@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/andriykrefer/cdsurfer/config"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestCursorMemory(t *testing.T) {
+	tmp := t.TempDir()
+	dir := filepath.Join(tmp, "dir")
+	os.Mkdir(dir, 0755)
+	for ix := 0; ix < 40; ix++ {
+		os.WriteFile(filepath.Join(dir, fmt.Sprintf("file%02d", ix)), nil, 0644)
+	}
+	m := Model{path: dir}
+	m.Init()
+	m.Update(tea.WindowSizeMsg{Width: 20, Height: 10})
+	m.setCursorToName("file30")
+	offset := m.rowOffset
+	m.goToPath(tmp)
+	m.setCursorToName("dir")
+	m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if m.CurrentItem().name != "file30" || m.rowOffset != offset || offset == 0 {
+		t.Fatal(m.CurrentItem().name, m.rowOffset)
+	}
+
+	// The focused item is gone: the cursor stays at its position
+	m.goToPath(tmp)
+	os.Remove(filepath.Join(dir, "file30"))
+	m.goToPath(dir)
+	if m.CurrentItem().name != "file31" {
+		t.Fatal(m.CurrentItem().name)
+	}
+	// Items added before it do not move the cursor away from it
+	os.WriteFile(filepath.Join(dir, "a new file"), nil, 0644)
+	m.goParent()
+	m.goToPath(dir)
+	if m.CurrentItem().name != "file31" {
+		t.Fatal(m.CurrentItem().name)
+	}
+
+	// Views restore the cursor when they are closed
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("t"), Alt: true})
+	m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if m.mode != modeList || m.CurrentItem().name != "file31" {
+		t.Fatal(m.mode, m.CurrentItem().name)
+	}
+}
+
+func TestSaveCursors(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", filepath.Join(tmp, "data"))
+	defer func(save bool) { config.SAVE_CURSORS = save }(config.SAVE_CURSORS)
+	config.SAVE_CURSORS = true
+	os.Mkdir(filepath.Join(tmp, "a"), 0755)
+	os.Mkdir(filepath.Join(tmp, "b"), 0755)
+
+	m := Model{path: tmp}
+	m.Init()
+	m.Update(tea.WindowSizeMsg{Width: 80, Height: 20})
+	m.setCursorToName("b")
+	if err := m.saveCursors(); err != nil {
+		t.Fatal(err)
+	}
+	m = Model{path: tmp}
+	m.Init()
+	m.Update(tea.WindowSizeMsg{Width: 80, Height: 20})
+	if m.CurrentItem().name != "b/" {
+		t.Fatal(m.CurrentItem().name)
+	}
+
+	cursors := map[string]dirCursor{}
+	for ix := 0; ix < cursorsMaxSz+10; ix++ {
+		cursors[fmt.Sprint("/dir", ix)] = dirCursor{Visited: int64(ix)}
+	}
+	saveCursors(cursors)
+	saved, err := loadCursors()
+	if _, found := saved["/dir9"]; err != nil || len(saved) != cursorsMaxSz || found {
+		t.Fatal(len(saved), err)
+	}
+}
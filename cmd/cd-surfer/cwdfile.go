@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type execDoneMsg struct {
+	err error
+}
+
+// quitWithCd quits changing the directory of the parent shell: the directory is written to the
+// --cwd-file or, by default, cmd is output to be evaluated by the shell
+func (thiss *Model) quitWithCd(cmd string) tea.Cmd {
+	if thiss.cwdFile != "" {
+		if err := os.WriteFile(thiss.cwdFile, []byte(thiss.path), 0600); err != nil {
+			thiss.err = err
+			return nil
+		}
+		return tea.Quit
+	}
+	fmt.Fprintf(os.Stderr, "\n")
+	fmt.Println(cmd)
+	return tea.Quit
+}
+
+// runFocusedFile executes the focused file, or opens it on the editor, and comes back to cd-surfer
+// when it finishes. It is used on --cwd-file mode, where nothing is evaluated by the parent shell
+func (thiss *Model) runFocusedFile() tea.Cmd {
+	name := thiss.CurrentItem().fileInfo.Name()
+	info, err := os.Stat(thiss.itemPath(thiss.CurrentItem()))
+	if err != nil {
+		thiss.err = err
+		return nil
+	}
+	var c *exec.Cmd
+	if isFileExecutable(info) {
+		c = exec.Command("./" + name)
+	} else {
+		c = exec.Command("sh", "-c", shellBash.editFileCmd(name, 1))
+	}
+	c.Dir = thiss.path
+	return tea.ExecProcess(c, func(err error) tea.Msg {
+		return execDoneMsg{err: err}
+	})
+}
+
+// cwdFileInitScript returns the wrapper function `cds` that changes to the directory written on the
+// --cwd-file, without evaluating anything
+func (sh shellEnum) cwdFileInitScript() string {
+	switch sh {
+	case shellFish:
+		return `function cds
+    set -l tmp (mktemp -t cd-surfer.XXXXXX)
+    command cd-surfer --cwd-file $tmp $argv
+    set -l dir (cat -- $tmp)
+    rm -f -- $tmp
+    if test -n "$dir"; and test "$dir" != "$PWD"
+        cd -- $dir
+    end
+end
+`
+	case shellNu:
+		return `def --env cds [...args] {
+    let tmp = (mktemp -t cd-surfer.XXXXXX)
+    ^cd-surfer --cwd-file $tmp ...$args
+    let dir = (open --raw $tmp)
+    rm -f $tmp
+    if ($dir | is-not-empty) { cd $dir }
+}
+`
+	case shellPwsh:
+		return `function cds {
+    $tmp = [System.IO.Path]::GetTempFileName()
+    & cd-surfer --cwd-file $tmp @args
+    $dir = Get-Content -Raw -LiteralPath $tmp
+    Remove-Item -LiteralPath $tmp
+    if ($dir) { Set-Location -LiteralPath $dir }
+}
+`
+	}
+	// bash and zsh
+	return `function cds {
+  local tmp dir
+  tmp="$(mktemp -t cd-surfer.XXXXXX)"
+  command cd-surfer --cwd-file "$tmp" "$@"
+  dir="$(cat -- "$tmp")"
+  rm -f -- "$tmp"
+  if [ -n "$dir" ] && [ "$dir" != "$PWD" ]; then
+    cd -- "$dir"
+  fi
+}
+`
+}
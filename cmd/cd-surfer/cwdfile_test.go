@@ -0,0 +1,46 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestQuitWithCdWritesCwdFile(t *testing.T) {
+	cwdFile := filepath.Join(t.TempDir(), "cwd")
+	m := Model{path: "/tmp/some dir", cwdFile: cwdFile}
+	if m.quitWithCd(m.shell.cdCmd(m.path)) == nil {
+		t.Fail()
+	}
+	data, _ := os.ReadFile(cwdFile)
+	if string(data) != "/tmp/some dir" {
+		t.Fail()
+	}
+}
+
+// TestCwdFileWrapper runs the bash wrapper with a fake cd-surfer that writes the directory to the
+// --cwd-file
+func TestCwdFileWrapper(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not found")
+	}
+	tmp := t.TempDir()
+	dir := filepath.Join(tmp, `a "$(touch PWNED)" b`)
+	os.Mkdir(dir, 0755)
+	bin := filepath.Join(tmp, "bin")
+	os.Mkdir(bin, 0755)
+	fake := "#!/bin/sh\n[ \"$1\" = --cwd-file ] && printf '%s' \"$TARGET\" > \"$2\"\n"
+	os.WriteFile(filepath.Join(bin, "cd-surfer"), []byte(fake), 0755)
+
+	c := exec.Command("bash", "-c", shellBash.cwdFileInitScript()+"cds; pwd")
+	c.Dir = tmp
+	c.Env = append(os.Environ(), "PATH="+bin+":"+os.Getenv("PATH"), "TARGET="+dir)
+	out, err := c.CombinedOutput()
+	if err != nil || string(out) != dir+"\n" {
+		t.Fatal(string(out), err)
+	}
+	if _, err := os.Stat(filepath.Join(tmp, "PWNED")); err == nil {
+		t.Fail()
+	}
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/andriykrefer/cdsurfer/config"
+	"github.com/andriykrefer/cdsurfer/term"
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Manual path input (modeEnterPath): a line editor with tab completion of the folders, expansion of ~,
+// $VAR and CDPATH, and a history of the entered paths
+
+const pathHistoryMaxSz = 100
+
+// expandPathInput returns the absolute path of a typed path. ~ is the home directory and $VAR or ${VAR}
+// are environment variables. Relative paths are searched on the CDPATH folders, then on cwd, like cd does
+func expandPathInput(input, cwd string) string {
+	path := input
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		home, _ := os.UserHomeDir()
+		path = home + path[1:]
+	}
+	path = os.Expand(path, os.Getenv)
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path)
+	}
+	isExplicitlyRelative := path == "." || path == ".." || strings.HasPrefix(path, "./") || strings.HasPrefix(path, "../")
+	if path != "" && !isExplicitlyRelative {
+		for _, dir := range filepath.SplitList(os.Getenv("CDPATH")) {
+			if dir == "" {
+				dir = cwd
+			}
+			if candidate := filepath.Join(dir, path); isDir(candidate) {
+				return candidate
+			}
+		}
+	}
+	return filepath.Join(cwd, path)
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// completePathInput completes the last segment of input with the folders that start with it. It returns
+// the completed input (up to the common prefix, if many folders match) and, if many match, the inputs
+// completed with each one
+func completePathInput(input, cwd string) (string, []string) {
+	if input == "~" {
+		return "~/", nil
+	}
+	dirPart, prefix := "", input
+	if ix := strings.LastIndex(input, "/"); ix >= 0 {
+		dirPart, prefix = input[:ix+1], input[ix+1:]
+	}
+	dirs := []string{expandPathInput(dirPart, cwd)}
+	if dirPart == "" { // The first segment of relative paths is also completed from CDPATH
+		dirs = []string{}
+		for _, dir := range filepath.SplitList(os.Getenv("CDPATH")) {
+			if dir != "" {
+				dirs = append(dirs, dir)
+			}
+		}
+		dirs = append(dirs, cwd)
+	}
+
+	found := map[string]bool{}
+	names := []string{}
+	for _, dir := range dirs {
+		entries, _ := os.ReadDir(dir)
+		for _, e := range entries {
+			name := e.Name()
+			if !strings.HasPrefix(name, prefix) || found[name] || (strings.HasPrefix(name, ".") && !strings.HasPrefix(prefix, ".")) {
+				continue
+			}
+			if !isDir(filepath.Join(dir, name)) { // Symlinks to folders are completed too
+				continue
+			}
+			found[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	if len(names) == 0 {
+		return input, nil
+	}
+	if len(names) == 1 {
+		return dirPart + names[0] + "/", nil
+	}
+	common := []rune(names[0]) // Runes, so multi-byte letters are not cut
+	candidates := []string{}
+	for _, name := range names {
+		for !strings.HasPrefix(name, string(common)) {
+			common = common[:len(common)-1]
+		}
+		candidates = append(candidates, dirPart+name+"/")
+	}
+	return dirPart + string(common), candidates
+}
+
+func pathHistoryPath() string {
+	return filepath.Join(config.DataDir(), "path_history")
+}
+
+// loadPathHistory reads the entered paths, the most recent last
+func loadPathHistory() ([]string, error) {
+	data, err := os.ReadFile(pathHistoryPath())
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	paths := []string{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		if scanner.Text() != "" {
+			paths = append(paths, scanner.Text())
+		}
+	}
+	return paths, scanner.Err()
+}
+
+// addToPathHistory adds path to the end of the history, removing its older occurrence
+func addToPathHistory(path string) error {
+	paths, err := loadPathHistory()
+	if err != nil {
+		return err
+	}
+	kept := []string{}
+	for _, p := range paths {
+		if p != path {
+			kept = append(kept, p)
+		}
+	}
+	kept = append(kept, path)
+	if len(kept) > pathHistoryMaxSz {
+		kept = kept[len(kept)-pathHistoryMaxSz:]
+	}
+	if err := os.MkdirAll(config.DataDir(), 0700); err != nil {
+		return err
+	}
+	tmp := pathHistoryPath() + ".tmp"
+	if err := os.WriteFile(tmp, []byte(strings.Join(kept, "\n")+"\n"), 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, pathHistoryPath())
+}
+
+// startEnterPath starts the path input with the current directory (~ for the home)
+func (thiss *Model) startEnterPath() {
+	path := strings.TrimSuffix(thiss.path, "/") + "/"
+	if home, err := os.UserHomeDir(); err == nil && home != "/" && strings.HasPrefix(path, home+"/") {
+		path = "~" + strings.TrimPrefix(path, home)
+	}
+	thiss.setPathInput(path)
+	history, err := loadPathHistory()
+	if err != nil {
+		thiss.err = err
+	}
+	thiss.pathHistory = history
+	thiss.pathHistoryIx = len(history)
+}
+
+// setPathInput replaces the input, with the cursor at its end. The completions are cleared
+func (thiss *Model) setPathInput(input string) {
+	thiss.inputPath = input
+	thiss.inputCursor = len([]rune(input))
+	thiss.pathCompletions = nil
+}
+
+// updateEnterPath handles the keys of the path input
+func (thiss *Model) updateEnterPath(msg tea.KeyMsg) {
+	runes := []rune(thiss.inputPath)
+	edit := func(newRunes []rune, cursor int) {
+		thiss.inputPath = string(newRunes)
+		thiss.inputCursor = cursor
+		thiss.pathCompletions = nil
+	}
+	switch {
+	case msg.Type == tea.KeyEsc || key.Matches(msg, keyQuitWithoutCd):
+		thiss.changeMode(modeList)
+	case msg.Type == tea.KeyEnter:
+		path := expandPathInput(thiss.inputPath, thiss.path)
+		if !thiss.isPathOk(path) {
+			return
+		}
+		thiss.changeMode(modeList)
+		thiss.goToPath(path)
+		if thiss.err == nil {
+			thiss.err = addToPathHistory(path)
+		}
+	case msg.Type == tea.KeyTab:
+		thiss.completePath()
+	case (msg.Type == tea.KeyRunes && !msg.Alt) || msg.Type == tea.KeySpace:
+		typed := msg.Runes
+		if msg.Type == tea.KeySpace {
+			typed = []rune(" ")
+		}
+		newRunes := append(append(append([]rune{}, runes[:thiss.inputCursor]...), typed...), runes[thiss.inputCursor:]...)
+		edit(newRunes, thiss.inputCursor+len(typed))
+	case msg.Type == tea.KeyBackspace && len(runes) == 0: // Like on the search, backspace on empty exits
+		thiss.changeMode(modeList)
+	case msg.Type == tea.KeyBackspace && thiss.inputCursor > 0:
+		edit(append(append([]rune{}, runes[:thiss.inputCursor-1]...), runes[thiss.inputCursor:]...), thiss.inputCursor-1)
+	case msg.Type == tea.KeyDelete && thiss.inputCursor < len(runes):
+		edit(append(append([]rune{}, runes[:thiss.inputCursor]...), runes[thiss.inputCursor+1:]...), thiss.inputCursor)
+	case msg.Type == tea.KeyCtrlU:
+		edit(nil, 0)
+	case msg.Type == tea.KeyLeft:
+		thiss.inputCursor = max(thiss.inputCursor-1, 0)
+	case msg.Type == tea.KeyRight:
+		thiss.inputCursor = min(thiss.inputCursor+1, len(runes))
+	case msg.Type == tea.KeyHome || msg.Type == tea.KeyCtrlA:
+		thiss.inputCursor = 0
+	case msg.Type == tea.KeyEnd || msg.Type == tea.KeyCtrlE:
+		thiss.inputCursor = len(runes)
+	case msg.Type == tea.KeyUp && thiss.pathHistoryIx > 0:
+		if thiss.pathHistoryIx == len(thiss.pathHistory) {
+			thiss.pathHistoryDraft = thiss.inputPath
+		}
+		thiss.pathHistoryIx--
+		thiss.setPathInput(thiss.pathHistory[thiss.pathHistoryIx])
+	case msg.Type == tea.KeyDown && thiss.pathHistoryIx < len(thiss.pathHistory):
+		thiss.pathHistoryIx++
+		if thiss.pathHistoryIx == len(thiss.pathHistory) {
+			thiss.setPathInput(thiss.pathHistoryDraft)
+		} else {
+			thiss.setPathInput(thiss.pathHistory[thiss.pathHistoryIx])
+		}
+	}
+}
+
+// completePath completes the input before the cursor. When many folders match, pressing tab again
+// cycles through them
+func (thiss *Model) completePath() {
+	runes := []rune(thiss.inputPath)
+	if len(thiss.pathCompletions) > 0 {
+		thiss.pathCompletionIx = (thiss.pathCompletionIx + 1) % len(thiss.pathCompletions)
+		completed := thiss.pathCompletions[thiss.pathCompletionIx]
+		thiss.inputPath = completed + thiss.pathCompletionTail
+		thiss.inputCursor = len([]rune(completed))
+		return
+	}
+	tail := string(runes[thiss.inputCursor:])
+	completed, candidates := completePathInput(string(runes[:thiss.inputCursor]), thiss.path)
+	thiss.inputPath = completed + tail
+	thiss.inputCursor = len([]rune(completed))
+	thiss.pathCompletions = candidates
+	thiss.pathCompletionIx = -1
+	thiss.pathCompletionTail = tail
+}
+
+// renderPathInput returns the input with colorFn and the cursor on it
+func (thiss *Model) renderPathInput(colorFn func(s string, isBg bool) string) string {
+	runes := []rune(thiss.inputPath)
+	o := colorFn(string(runes[:thiss.inputCursor]), false)
+	if thiss.inputCursor == len(runes) {
+		return o + term.Violet("_", false)
+	}
+	return o + term.Violet(string(runes[thiss.inputCursor]), true) + colorFn(string(runes[thiss.inputCursor+1:]), false)
+}
+
+// renderPathCompletions returns the names of the folders that match, the chosen one highlighted
+func (thiss *Model) renderPathCompletions() string {
+	names := []string{}
+	for ix, c := range thiss.pathCompletions {
+		name := filepath.Base(c) + "/"
+		if ix == thiss.pathCompletionIx {
+			name = term.Violet(name, false)
+		}
+		names = append(names, name)
+	}
+	return strings.Join(names, "  ")
+}
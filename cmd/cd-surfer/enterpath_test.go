@@ -0,0 +1,146 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/gookit/color"
+)
+
+func TestExpandPathInput(t *testing.T) {
+	tmp := t.TempDir()
+	os.MkdirAll(filepath.Join(tmp, "projects", "api"), 0755)
+	os.MkdirAll(filepath.Join(tmp, "cwd", "docs"), 0755)
+	home, _ := os.UserHomeDir()
+	t.Setenv("MY_DIR", tmp)
+	t.Setenv("CDPATH", filepath.Join(tmp, "projects"))
+	cwd := filepath.Join(tmp, "cwd")
+	cases := map[string]string{
+		"~":                home,
+		"~/x":              filepath.Join(home, "x"),
+		"$MY_DIR/a":        filepath.Join(tmp, "a"),
+		"${MY_DIR}/b/../c": filepath.Join(tmp, "c"),
+		"/usr/":            "/usr",
+		"docs":             filepath.Join(cwd, "docs"),
+		"api":              filepath.Join(tmp, "projects", "api"), // From CDPATH
+		"./api":            filepath.Join(cwd, "api"),
+		"..":               tmp,
+	}
+	for input, expected := range cases {
+		if path := expandPathInput(input, cwd); path != expected {
+			t.Error(input, path)
+		}
+	}
+}
+
+func TestCompletePathInput(t *testing.T) {
+	tmp := t.TempDir()
+	for _, dir := range []string{"src/cmd", "src/config", "src/.hidden", "projects/web", "intl/èb", "intl/éa"} {
+		os.MkdirAll(filepath.Join(tmp, dir), 0755)
+	}
+	os.WriteFile(filepath.Join(tmp, "src", "code.go"), nil, 0644)
+	t.Setenv("CDPATH", filepath.Join(tmp, "projects"))
+
+	completed, candidates := completePathInput("sr", tmp)
+	if completed != "src/" || candidates != nil {
+		t.Fatal(completed, candidates)
+	}
+	completed, candidates = completePathInput("src/c", tmp) // Files are not completed
+	if completed != "src/c" || strings.Join(candidates, " ") != "src/cmd/ src/config/" {
+		t.Fatal(completed, candidates)
+	}
+	completed, _ = completePathInput("src/co", tmp)
+	if completed != "src/config/" {
+		t.Fatal(completed)
+	}
+	completed, _ = completePathInput(tmp+"/src/.h", "/")
+	if completed != tmp+"/src/.hidden/" {
+		t.Fatal(completed)
+	}
+	completed, _ = completePathInput("we", tmp) // From CDPATH
+	if completed != "web/" {
+		t.Fatal(completed)
+	}
+	completed, _ = completePathInput("intl/", tmp) // The common prefix does not cut multi-byte letters
+	if completed != "intl/" {
+		t.Fatalf("%q", completed)
+	}
+}
+
+func TestEnterPathMode(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", filepath.Join(tmp, "data"))
+	t.Setenv("CDPATH", "")
+	os.MkdirAll(filepath.Join(tmp, "src", "cmd"), 0755)
+	os.MkdirAll(filepath.Join(tmp, "src", "config"), 0755)
+	m := Model{path: tmp}
+	m.Init()
+	m.Update(tea.WindowSizeMsg{Width: 80, Height: 20})
+	typeText := func(s string) {
+		m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)})
+	}
+
+	m.Update(tea.KeyMsg{Type: tea.KeyCtrlL})
+	if m.mode != modeEnterPath || m.inputPath != tmp+"/" {
+		t.Fatal(m.mode, m.inputPath)
+	}
+	// Backspace on an empty input exits, instead of panicking
+	m.Update(tea.KeyMsg{Type: tea.KeyCtrlU})
+	m.Update(tea.KeyMsg{Type: tea.KeyBackspace})
+	if m.mode != modeList {
+		t.Fatal(m.mode)
+	}
+	m.Update(tea.KeyMsg{Type: tea.KeyCtrlL})
+	m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if m.mode != modeList || m.path != tmp {
+		t.Fatal(m.mode, m.path)
+	}
+
+	// Completion, cycling through the candidates
+	m.Update(tea.KeyMsg{Type: tea.KeyCtrlL})
+	typeText("s")
+	m.Update(tea.KeyMsg{Type: tea.KeyTab})
+	typeText("c")
+	m.Update(tea.KeyMsg{Type: tea.KeyTab})
+	m.Update(tea.KeyMsg{Type: tea.KeyTab})
+	if m.inputPath != tmp+"/src/cmd/" || !strings.Contains(color.ClearCode(m.View()), "cmd/  config/") {
+		t.Fatal(m.inputPath)
+	}
+	m.Update(tea.KeyMsg{Type: tea.KeyTab})
+	if m.inputPath != tmp+"/src/config/" {
+		t.Fatal(m.inputPath)
+	}
+	// Editing in the middle of the line
+	m.Update(tea.KeyMsg{Type: tea.KeyLeft})
+	m.Update(tea.KeyMsg{Type: tea.KeyLeft})
+	m.Update(tea.KeyMsg{Type: tea.KeyBackspace})
+	m.Update(tea.KeyMsg{Type: tea.KeyDelete})
+	typeText("xx")
+	if m.inputPath != tmp+"/src/confxx/" || m.inputCursor != len([]rune(tmp+"/src/confxx")) {
+		t.Fatal(m.inputPath, m.inputCursor)
+	}
+	m.Update(tea.KeyMsg{Type: tea.KeyEnter}) // Invalid path: nothing happens
+	if m.mode != modeEnterPath {
+		t.Fatal(m.mode)
+	}
+	m.Update(tea.KeyMsg{Type: tea.KeyCtrlU})
+	typeText("src/cmd")
+	m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if m.mode != modeList || m.path != filepath.Join(tmp, "src", "cmd") {
+		t.Fatal(m.mode, m.path, m.err)
+	}
+
+	// History of the entered paths
+	m.Update(tea.KeyMsg{Type: tea.KeyCtrlL})
+	m.Update(tea.KeyMsg{Type: tea.KeyUp})
+	if m.inputPath != filepath.Join(tmp, "src", "cmd") {
+		t.Fatal(m.inputPath)
+	}
+	m.Update(tea.KeyMsg{Type: tea.KeyDown})
+	if m.inputPath != filepath.Join(tmp, "src", "cmd")+"/" {
+		t.Fatal(m.inputPath)
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// copyPath copies src to dst recursively. Symlinks are recreated (not followed) and permissions and
+// modification times are kept
+func copyPath(src, dst string) error {
+	info, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+	switch {
+	case isFileSymlink(info):
+		target, err := os.Readlink(src)
+		if err != nil {
+			return err
+		}
+		return os.Symlink(target, dst)
+	case info.IsDir():
+		return copyDir(src, dst, info)
+	case info.Mode().IsRegular():
+		return copyFile(src, dst, info)
+	}
+	return fmt.Errorf("cannot copy %s: unsupported file type", src)
+}
+
+func copyDir(src, dst string, info os.FileInfo) (err error) {
+	// Create it writable, so the content can be copied even from read-only folders. The original
+	// permissions are set at the end
+	if err := os.Mkdir(dst, 0700); err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			removePartialCopy(dst)
+		}
+	}()
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		err := copyPath(filepath.Join(src, e.Name()), filepath.Join(dst, e.Name()))
+		if err != nil {
+			return err
+		}
+	}
+	if err := os.Chmod(dst, info.Mode().Perm()); err != nil {
+		return err
+	}
+	return os.Chtimes(dst, info.ModTime(), info.ModTime())
+}
+
+func copyFile(src, dst string, info os.FileInfo) (err error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			os.Remove(dst)
+		}
+	}()
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	// OpenFile permissions are affected by umask
+	if err := os.Chmod(dst, info.Mode().Perm()); err != nil {
+		return err
+	}
+	return os.Chtimes(dst, info.ModTime(), info.ModTime())
+}
+
+// removePartialCopy removes what was copied to dst before a copy failed. Folders already copied may
+// be read-only, so they are made writable first
+func removePartialCopy(dst string) {
+	filepath.WalkDir(dst, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && d.IsDir() {
+			os.Chmod(path, 0700)
+		}
+		return nil
+	})
+	os.RemoveAll(dst)
+}
+
+// movePath renames src to dst. When they are on different file systems, it falls back to copy and
+// remove
+func movePath(src, dst string) error {
+	err := os.Rename(src, dst)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+	if err := copyPath(src, dst); err != nil {
+		return err
+	}
+	return os.RemoveAll(src)
+}
+
+// uniqueDestPath returns path if nothing exists there, otherwise a sibling like "name (1).ext"
+func uniqueDestPath(path string) string {
+	if _, err := os.Lstat(path); err != nil {
+		return path
+	}
+	dir := filepath.Dir(path)
+	base := filepath.Base(path)
+	ext := filepath.Ext(base)
+	if ext == base { // dotfiles like .bashrc
+		ext = ""
+	}
+	stem := strings.TrimSuffix(base, ext)
+	for n := 1; ; n++ {
+		candidate := filepath.Join(dir, stem+" ("+strconv.Itoa(n)+")"+ext)
+		if _, err := os.Lstat(candidate); err != nil {
+			return candidate
+		}
+	}
+}
+
+// isSubPath returns true when path is equal to or inside parent
+func isSubPath(parent, path string) bool {
+	parent = filepath.Clean(parent)
+	path = filepath.Clean(path)
+	return path == parent || strings.HasPrefix(path, strings.TrimSuffix(parent, "/")+"/")
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestCopyPath(t *testing.T) {
+	tmp := t.TempDir()
+	src := filepath.Join(tmp, "src")
+	os.MkdirAll(filepath.Join(src, "sub"), 0755)
+	os.WriteFile(filepath.Join(src, "sub", "script.sh"), []byte("echo hi"), 0750)
+	os.Symlink("sub/script.sh", filepath.Join(src, "link"))
+
+	dst := filepath.Join(tmp, "dst")
+	if err := copyPath(src, dst); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dst, "sub", "script.sh"))
+	if err != nil || string(data) != "echo hi" {
+		t.Fail()
+	}
+	info, err := os.Stat(filepath.Join(dst, "sub", "script.sh"))
+	if err != nil || info.Mode().Perm() != 0750 {
+		t.Fail()
+	}
+	target, err := os.Readlink(filepath.Join(dst, "link"))
+	if err != nil || target != "sub/script.sh" {
+		t.Fail()
+	}
+}
+
+func TestCopyPathFailureRemovesDst(t *testing.T) {
+	tmp := t.TempDir()
+	src := filepath.Join(tmp, "src")
+	os.MkdirAll(filepath.Join(src, "a"), 0755)
+	os.WriteFile(filepath.Join(src, "a", "file"), []byte("a"), 0644)
+	os.Chmod(filepath.Join(src, "a"), 0555)
+	t.Cleanup(func() { os.Chmod(filepath.Join(src, "a"), 0755) })
+	// Fifos cannot be copied
+	if err := syscall.Mkfifo(filepath.Join(src, "z-fifo"), 0644); err != nil {
+		t.Skip(err)
+	}
+
+	dst := filepath.Join(tmp, "dst")
+	if err := copyPath(src, dst); err == nil {
+		t.Fatal("copied a fifo")
+	}
+	if _, err := os.Lstat(dst); err == nil {
+		t.Fatal("partial copy left behind")
+	}
+
+	// An existing destination is not removed
+	os.WriteFile(dst, []byte("dst"), 0644)
+	if err := copyPath(filepath.Join(src, "a", "file"), dst); err == nil {
+		t.Fail()
+	}
+	if data, _ := os.ReadFile(dst); string(data) != "dst" {
+		t.Fail()
+	}
+}
+
+func TestUniqueDestPath(t *testing.T) {
+	tmp := t.TempDir()
+	os.WriteFile(filepath.Join(tmp, "a.txt"), nil, 0644)
+	os.WriteFile(filepath.Join(tmp, "a (1).txt"), nil, 0644)
+	os.WriteFile(filepath.Join(tmp, ".env"), nil, 0644)
+
+	if uniqueDestPath(filepath.Join(tmp, "b.txt")) != filepath.Join(tmp, "b.txt") {
+		t.Fail()
+	}
+	if uniqueDestPath(filepath.Join(tmp, "a.txt")) != filepath.Join(tmp, "a (2).txt") {
+		t.Fail()
+	}
+	if uniqueDestPath(filepath.Join(tmp, ".env")) != filepath.Join(tmp, ".env (1)") {
+		t.Fail()
+	}
+}
+
+func TestIsSubPath(t *testing.T) {
+	if !isSubPath("/a/b", "/a/b/c") || !isSubPath("/a/b", "/a/b") {
+		t.Fail()
+	}
+	if isSubPath("/a/b", "/a/bc") || isSubPath("/a/b", "/a") {
+		t.Fail()
+	}
+	if !isSubPath("/", "/a") {
+		t.Fail()
+	}
+}
+
+func TestPasteCutKeepsItemsNotMoved(t *testing.T) {
+	tmp := t.TempDir()
+	dst := filepath.Join(tmp, "dst")
+	os.Mkdir(dst, 0755)
+	os.WriteFile(filepath.Join(tmp, "a"), nil, 0644)
+	m := Model{}
+	m.Init()
+	m.path = dst
+	m.clipboard = []string{filepath.Join(tmp, "a"), tmp, filepath.Join(tmp, "b")}
+	m.clipboardOp = clipboardOpCut
+	m.paste()
+	if m.err == nil {
+		t.Fatal("pasting into itself must fail")
+	}
+	if _, err := os.Stat(filepath.Join(dst, "a")); err != nil {
+		t.Fatal(err)
+	}
+	if len(m.clipboard) != 2 || m.clipboard[0] != tmp || m.clipboard[1] != filepath.Join(tmp, "b") {
+		t.Fatal(m.clipboard)
+	}
+}
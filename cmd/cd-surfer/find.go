@@ -0,0 +1,270 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/andriykrefer/cdsurfer/config"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Recursive search (modeFind): the names beneath the current directory are matched on a background
+// walk, and the results are streamed to the list
+
+const findBatchSz = 256
+
+type findResultsMsg struct {
+	gen     int // Search generation. Results of older searches are dropped
+	items   []Item
+	done    bool
+	results chan Item
+}
+
+// startFind cancels the running search and starts a new one for the search input
+func (thiss *Model) startFind() tea.Cmd {
+	root, input := thiss.path, thiss.searchInput
+	return thiss.startBackgroundSearch(func(ctx context.Context, results chan<- Item) {
+		findNames(ctx, root, input, results)
+	})
+}
+
+// startBackgroundSearch cancels the running search and runs search on background, if the search
+// input is not empty. search must close results when it ends
+func (thiss *Model) startBackgroundSearch(search func(ctx context.Context, results chan<- Item)) tea.Cmd {
+	thiss.stopFind()
+	thiss.findGen++
+	thiss.filteredItems = []Item{}
+	thiss.items = thiss.filteredItems
+	thiss.cursorIx = 0
+	thiss.rowOffset = 0
+	if thiss.searchInput == "" {
+		return nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	thiss.findCancel = cancel
+	thiss.findRunning = true
+	results := make(chan Item, findBatchSz)
+	go search(ctx, results)
+	return waitFindResults(thiss.findGen, results)
+}
+
+// stopFind cancels the running search, if any
+func (thiss *Model) stopFind() {
+	if thiss.findCancel != nil {
+		thiss.findCancel()
+		thiss.findCancel = nil
+	}
+	thiss.findRunning = false
+}
+
+// waitFindResults waits for the next results, returning up to findBatchSz of them at once
+func waitFindResults(gen int, results chan Item) tea.Cmd {
+	return func() tea.Msg {
+		item, ok := <-results
+		if !ok {
+			return findResultsMsg{gen: gen, done: true}
+		}
+		items := []Item{item}
+		for len(items) < findBatchSz {
+			select {
+			case item, ok := <-results:
+				if !ok {
+					return findResultsMsg{gen: gen, items: items, done: true}
+				}
+				items = append(items, item)
+			default:
+				return findResultsMsg{gen: gen, items: items, results: results}
+			}
+		}
+		return findResultsMsg{gen: gen, items: items, results: results}
+	}
+}
+
+func (thiss *Model) addFindResults(msg findResultsMsg) tea.Cmd {
+	if msg.gen != thiss.findGen || (thiss.mode != modeFind && thiss.mode != modeGrep) {
+		return nil
+	}
+	thiss.filteredItems = append(thiss.filteredItems, msg.items...)
+	thiss.items = thiss.filteredItems
+	thiss.calculateColsAndRows()
+	if msg.done {
+		thiss.stopFind()
+		return nil
+	}
+	return waitFindResults(msg.gen, msg.results)
+}
+
+// typeFind adds the typed runes to the search input and restarts the search
+func (thiss *Model) typeFind(runes []rune) tea.Cmd {
+	thiss.searchInput += string(runes)
+	return thiss.startFind()
+}
+
+// openFindResult goes to the directory of the focused result, with the cursor on it
+func (thiss *Model) openFindResult() {
+	if len(thiss.items) == 0 {
+		return
+	}
+	fullPath := thiss.CurrentItem().fullPath
+	thiss.changeMode(modeList)
+	thiss.goToPath(filepath.Dir(fullPath))
+	thiss.setCursorToName(filepath.Base(fullPath))
+}
+
+// findSkipNames returns the names of the directories that are not searched
+func findSkipNames() map[string]bool {
+	names := map[string]bool{}
+	for _, name := range strings.Split(config.FIND_SKIP, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names[name] = true
+		}
+	}
+	return names
+}
+
+// findNames walks root sending the entries whose names match input, until the walk ends or ctx is
+// canceled. results is closed at the end
+func findNames(ctx context.Context, root, input string, results chan<- Item) {
+	defer close(results)
+	caseSensitive := isCaseSensitive(input)
+	walkTree(ctx, root, func(fullPath, rel string, e os.DirEntry) bool {
+		if _, positions, ok := searchMatch(input, e.Name(), caseSensitive); ok {
+			select {
+			case results <- findItem(fullPath, rel, e, positions):
+			case <-ctx.Done():
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// walkTree calls visit for the entries beneath root, until visit returns false or ctx is canceled.
+// Directories deeper than config.FIND_MAX_DEPTH, named on config.FIND_SKIP or ignored by .gitignore
+// files are skipped
+func walkTree(ctx context.Context, root string, visit func(fullPath, rel string, e os.DirEntry) bool) {
+	skip := findSkipNames()
+	var walk func(dir, rel string, depth int, rules []ignoreRule) bool
+	walk = func(dir, rel string, depth int, rules []ignoreRule) bool {
+		if ctx.Err() != nil {
+			return false
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return true // Unreadable directories are skipped
+		}
+		if config.FIND_GITIGNORE {
+			if data, err := os.ReadFile(filepath.Join(dir, ".gitignore")); err == nil {
+				rules = append(rules[:len(rules):len(rules)], parseGitignore(rel, string(data))...)
+			}
+		}
+		for _, e := range entries {
+			entryRel := path.Join(rel, e.Name())
+			if isIgnored(rules, entryRel, e.IsDir()) || (e.IsDir() && skip[e.Name()]) {
+				continue
+			}
+			if !visit(filepath.Join(dir, e.Name()), entryRel, e) {
+				return false
+			}
+			if e.IsDir() && depth < config.FIND_MAX_DEPTH {
+				if !walk(filepath.Join(dir, e.Name()), entryRel, depth+1, rules) {
+					return false
+				}
+			}
+		}
+		return true
+	}
+	walk(root, "", 1, nil)
+}
+
+// findItem returns the list item of a search result. Its name is the path relative to the search root,
+// with the matched runes of the base name emphasized
+func findItem(fullPath, rel string, e os.DirEntry, positions []int) Item {
+	info, err := e.Info()
+	if err != nil {
+		info = unreadableFileInfo{e}
+	}
+	name := rel
+	if e.IsDir() {
+		name += "/"
+	}
+	dirPrefix := len(rel) - len(e.Name())
+	spans := runeSpans(e.Name(), positions)
+	for ix := range spans {
+		spans[ix] += dirPrefix
+	}
+	item := pathItem(name, fullPath, info, err)
+	item.emphasisTextIx = spans
+	return item
+}
+
+// ignoreRule is a pattern of a .gitignore file. A subset of the syntax is supported: comments,
+// negation (!), directory only patterns (trailing /), patterns anchored to the .gitignore directory
+// (with a / other than the trailing one), a leading **/ and a trailing /**
+type ignoreRule struct {
+	base     string // Directory of the .gitignore, relative to the search root
+	pattern  string
+	negate   bool
+	dirOnly  bool
+	anchored bool
+}
+
+func parseGitignore(base, content string) []ignoreRule {
+	rules := []ignoreRule{}
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimRight(line, "\r")
+		line = strings.TrimRight(line, " ")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		r := ignoreRule{base: base}
+		if strings.HasPrefix(line, "!") {
+			r.negate = true
+			line = line[1:]
+		}
+		line = strings.TrimPrefix(line, `\`) // Escaped leading # or !
+		if strings.HasSuffix(line, "/") {
+			r.dirOnly = true
+			line = strings.TrimSuffix(line, "/")
+		}
+		line = strings.TrimPrefix(line, "**/")
+		if strings.Contains(line, "/") {
+			r.anchored = true
+			line = strings.TrimPrefix(line, "/")
+		}
+		line = strings.TrimSuffix(line, "/**") // Ignoring the folder contents is ignoring the folder
+		if line == "" {
+			continue
+		}
+		r.pattern = line
+		rules = append(rules, r)
+	}
+	return rules
+}
+
+// isIgnored returns true if the last rule that matches rel (relative to the search root) ignores it
+func isIgnored(rules []ignoreRule, rel string, isDir bool) bool {
+	ignored := false
+	for _, r := range rules {
+		if r.dirOnly && !isDir {
+			continue
+		}
+		sub := rel
+		if r.base != "" {
+			if !strings.HasPrefix(rel, r.base+"/") {
+				continue
+			}
+			sub = strings.TrimPrefix(rel, r.base+"/")
+		}
+		if !r.anchored {
+			sub = path.Base(sub)
+		}
+		if matched, _ := path.Match(r.pattern, sub); matched {
+			ignored = !r.negate
+		}
+	}
+	return ignored
+}
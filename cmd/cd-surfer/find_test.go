@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/andriykrefer/cdsurfer/config"
+)
+
+func TestFindNames(t *testing.T) {
+	tmp := t.TempDir()
+	for _, p := range []string{
+		"model.go", "a/b/model_test.go", "a/b/c/d/model.txt", ".git/model", "node_modules/x/model.js",
+		"build/model.o", "a/model.log", "a/keep/model.log", "a/B/Model.md",
+	} {
+		os.MkdirAll(filepath.Join(tmp, filepath.Dir(p)), 0755)
+		os.WriteFile(filepath.Join(tmp, p), nil, 0644)
+	}
+	os.WriteFile(filepath.Join(tmp, ".gitignore"), []byte("# Build\n/build/\n"), 0644)
+	os.WriteFile(filepath.Join(tmp, "a", ".gitignore"), []byte("*.log\n!keep/*.log\n"), 0644)
+	maxDepth := config.FIND_MAX_DEPTH
+	config.FIND_MAX_DEPTH = 3
+	t.Cleanup(func() { config.FIND_MAX_DEPTH = maxDepth })
+
+	find := func(input string) []string {
+		results := make(chan Item)
+		go findNames(context.Background(), tmp, input, results)
+		names := []string{}
+		for item := range results {
+			names = append(names, item.name)
+		}
+		sort.Strings(names)
+		return names
+	}
+	names := find("model")
+	expected := []string{"a/B/Model.md", "a/b/model_test.go", "a/keep/model.log", "model.go"}
+	if len(names) != len(expected) {
+		t.Fatal(names)
+	}
+	for ix := range names {
+		if names[ix] != expected[ix] {
+			t.Fatal(names)
+		}
+	}
+	if names := find("Model"); len(names) != 1 || names[0] != "a/B/Model.md" {
+		t.Fatal(names)
+	}
+}
+
+func TestFindNamesCancel(t *testing.T) {
+	tmp := t.TempDir()
+	for _, name := range []string{"a1", "a2", "a3"} {
+		os.WriteFile(filepath.Join(tmp, name), nil, 0644)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	results := make(chan Item)
+	go findNames(ctx, tmp, "a", results)
+	<-results
+	cancel()
+	for range results { // Closed after the cancel
+	}
+}
+
+func TestFindResultsMsg(t *testing.T) {
+	tmp := t.TempDir()
+	os.MkdirAll(filepath.Join(tmp, "x", "y"), 0755)
+	os.WriteFile(filepath.Join(tmp, "x", "y", "needle.txt"), nil, 0644)
+	m := Model{path: tmp, width: 80, height: 20}
+	m.Ls()
+	m.changeMode(modeFind)
+	m.typeFind([]rune("need"))
+	cmd := m.typeFind([]rune("le"))
+	for cmd != nil {
+		msg := cmd().(findResultsMsg)
+		cmd = m.addFindResults(msg)
+	}
+	if m.findRunning || len(m.items) != 1 || m.items[0].name != "x/y/needle.txt" {
+		t.Fatal(m.items)
+	}
+	if m.items[0].emphasisTextIx[0] != 4 || m.items[0].emphasisTextIx[1] != 10 {
+		t.Fatal(m.items[0].emphasisTextIx)
+	}
+	// Stale results are dropped
+	m.addFindResults(findResultsMsg{gen: m.findGen - 1, items: []Item{{name: "old"}}})
+	if len(m.items) != 1 {
+		t.Fail()
+	}
+
+	m.openFindResult()
+	if m.mode != modeList || m.path != filepath.Join(tmp, "x", "y") || m.CurrentItem().name != "needle.txt" {
+		t.Fatal(m.path, m.CurrentItem().name)
+	}
+}
+
+func TestIsIgnored(t *testing.T) {
+	rules := parseGitignore("", "*.o\n/dist\ndocs/*.md\n!docs/keep.md\n**/tmp/\nlogs/**\n\\#hash\n")
+	rules = append(rules, parseGitignore("sub", "local\n")...)
+	cases := []struct {
+		rel     string
+		isDir   bool
+		ignored bool
+	}{
+		{"x/y.o", false, true},
+		{"dist", true, true},
+		{"x/dist", true, false},
+		{"docs/a.md", false, true},
+		{"docs/keep.md", false, false},
+		{"x/tmp", true, true},
+		{"x/tmp", false, false},
+		{"logs", true, true},
+		{"#hash", false, true},
+		{"sub/local", false, true},
+		{"local", false, false},
+	}
+	for _, c := range cases {
+		if isIgnored(rules, c.rel, c.isDir) != c.ignored {
+			t.Error(c.rel)
+		}
+	}
+}
@@ -0,0 +1,368 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andriykrefer/cdsurfer/config"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Frecency database: the visited directories, with a visit count (rank) and the time of the last visit.
+// Directories are ranked by frequency and recency, like zoxide. It is stored on
+// $XDG_DATA_HOME/cd-surfer/frecency, one "rank<tab>last visit (unix time)<tab>path" line per directory.
+// The visits are kept on memory while browsing and added to the database on exit, under a lock file, so
+// the instances running at the same time do not lose each other's visits
+
+// frecencyMaxRank is the sum of ranks that ages the database: when it is exceeded, the ranks are scaled
+// down and the directories that fall under 1 are forgotten
+const frecencyMaxRank = 10000
+
+const frecencyLockTimeout = 2 * time.Second
+
+// frecencyLockStale is the age of a lock file left by an instance that crashed
+const frecencyLockStale = 10 * time.Second
+
+type jumpCheckedMsg struct {
+	gen   int                    // Jump view generation. Checks of older views are dropped
+	infos map[string]os.FileInfo // Info of the directories that still exist
+}
+
+type frecencyEntry struct {
+	path      string
+	rank      float64
+	lastVisit int64 // Unix time
+}
+
+func frecencyPath() string {
+	return filepath.Join(config.DataDir(), "frecency")
+}
+
+// loadFrecency reads the database. A missing file is an empty database
+func loadFrecency() ([]frecencyEntry, error) {
+	data, err := os.ReadFile(frecencyPath())
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	entries := []frecencyEntry{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.SplitN(scanner.Text(), "\t", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		rank, err1 := strconv.ParseFloat(fields[0], 64)
+		lastVisit, err2 := strconv.ParseInt(fields[1], 10, 64)
+		if err1 != nil || err2 != nil || fields[2] == "" {
+			continue
+		}
+		entries = append(entries, frecencyEntry{path: fields[2], rank: rank, lastVisit: lastVisit})
+	}
+	return entries, scanner.Err()
+}
+
+// saveFrecency ages and writes the database. It is written to a temporary file first, so it is never left
+// half written
+func saveFrecency(entries []frecencyEntry) error {
+	total := 0.0
+	for _, e := range entries {
+		total += e.rank
+	}
+	var buf bytes.Buffer
+	for _, e := range entries {
+		if total > frecencyMaxRank {
+			e.rank *= 0.9 * frecencyMaxRank / total
+			if e.rank < 1 {
+				continue
+			}
+		}
+		fmt.Fprintf(&buf, "%s\t%d\t%s\n", strconv.FormatFloat(e.rank, 'f', -1, 64), e.lastVisit, e.path)
+	}
+	if err := os.MkdirAll(config.DataDir(), 0700); err != nil {
+		return err
+	}
+	tmp := frecencyPath() + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, frecencyPath())
+}
+
+// mergeFrecency adds the ranks of entries to the database ones, keeping the latest visit
+func mergeFrecency(db, entries []frecencyEntry) []frecencyEntry {
+	ixByPath := map[string]int{}
+	for ix, e := range db {
+		ixByPath[e.path] = ix
+	}
+	for _, e := range entries {
+		ix, found := ixByPath[e.path]
+		if !found {
+			ixByPath[e.path] = len(db)
+			db = append(db, e)
+			continue
+		}
+		db[ix].rank += e.rank
+		if e.lastVisit > db[ix].lastVisit {
+			db[ix].lastVisit = e.lastVisit
+		}
+	}
+	return db
+}
+
+func frecencyLockPath() string {
+	return frecencyPath() + ".lock"
+}
+
+// lockFrecency creates the lock file of the database, waiting while another instance has it. It returns
+// the function that removes it
+func lockFrecency() (func(), error) {
+	if err := os.MkdirAll(config.DataDir(), 0700); err != nil {
+		return nil, err
+	}
+	deadline := time.Now().Add(frecencyLockTimeout)
+	for {
+		f, err := os.OpenFile(frecencyLockPath(), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(frecencyLockPath()) }, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, err
+		}
+		if info, err := os.Stat(frecencyLockPath()); err == nil && time.Since(info.ModTime()) > frecencyLockStale {
+			os.Remove(frecencyLockPath())
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("%s is locked by another instance", frecencyPath())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// addToFrecency merges entries into the database, with the database locked
+func addToFrecency(entries []frecencyEntry) error {
+	unlock, err := lockFrecency()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	db, err := loadFrecency()
+	if err != nil {
+		return err
+	}
+	return saveFrecency(mergeFrecency(db, entries))
+}
+
+// recordVisit adds a visit of path to the database
+func recordVisit(path string, now time.Time) error {
+	return addToFrecency([]frecencyEntry{{path: filepath.Clean(path), rank: 1, lastVisit: now.Unix()}})
+}
+
+// frecencyScore weights the rank by the time since the last visit
+func frecencyScore(e frecencyEntry, now time.Time) float64 {
+	age := now.Sub(time.Unix(e.lastVisit, 0))
+	switch {
+	case age < time.Hour:
+		return e.rank * 4
+	case age < 24*time.Hour:
+		return e.rank * 2
+	case age < 7*24*time.Hour:
+		return e.rank / 2
+	}
+	return e.rank / 4
+}
+
+// frecencyMatches returns true if the terms are found on path in order, the last one on its last
+// component (smart-case)
+func frecencyMatches(path string, terms []string) bool {
+	if len(terms) == 0 {
+		return true
+	}
+	if !isCaseSensitive(strings.Join(terms, " ")) {
+		path = strings.ToLower(path)
+		lowered := make([]string, len(terms))
+		for ix, term := range terms {
+			lowered[ix] = strings.ToLower(term)
+		}
+		terms = lowered
+	}
+	start := 0
+	for _, term := range terms {
+		ix := strings.Index(path[start:], term)
+		if ix < 0 {
+			return false
+		}
+		start += ix + len(term)
+	}
+	return strings.Contains(filepath.Base(path), terms[len(terms)-1])
+}
+
+// rankFrecency returns the entries that match the terms, the best ranked first
+func rankFrecency(entries []frecencyEntry, terms []string, now time.Time) []frecencyEntry {
+	matches := []frecencyEntry{}
+	for _, e := range entries {
+		if frecencyMatches(e.path, terms) {
+			matches = append(matches, e)
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return frecencyScore(matches[i], now) > frecencyScore(matches[j], now)
+	})
+	return matches
+}
+
+// queryFrecency returns the directories that match the terms and still exist, the best ranked first
+func queryFrecency(terms []string, now time.Time) ([]frecencyEntry, error) {
+	db, err := loadFrecency()
+	if err != nil {
+		return nil, err
+	}
+	matches := []frecencyEntry{}
+	for _, e := range rankFrecency(db, terms, now) {
+		if info, err := os.Stat(e.path); err == nil && info.IsDir() {
+			matches = append(matches, e)
+		}
+	}
+	return matches, nil
+}
+
+// recordCurrentDir records the visit of the current directory, to be saved on exit. Archive folders are
+// not recorded
+func (thiss *Model) recordCurrentDir() {
+	if thiss.archive != nil {
+		return
+	}
+	visit := frecencyEntry{path: filepath.Clean(thiss.path), rank: 1, lastVisit: time.Now().Unix()}
+	thiss.visits = mergeFrecency(thiss.visits, []frecencyEntry{visit})
+}
+
+// saveVisits adds the visits of the session to the database, on exit
+func (thiss *Model) saveVisits() error {
+	if len(thiss.visits) == 0 {
+		return nil
+	}
+	return addToFrecency(thiss.visits)
+}
+
+// startJump loads the database for the jump view, with the visits not saved yet. The directories that
+// still exist are checked on background
+func (thiss *Model) startJump() tea.Cmd {
+	db, err := loadFrecency()
+	if err != nil {
+		thiss.err = err
+	}
+	thiss.jumpEntries = mergeFrecency(db, thiss.visits)
+	thiss.jumpInfos = nil
+	thiss.jumpGen++
+	gen := thiss.jumpGen
+	paths := make([]string, len(thiss.jumpEntries))
+	for ix, e := range thiss.jumpEntries {
+		paths[ix] = e.path
+	}
+	return func() tea.Msg {
+		infos := map[string]os.FileInfo{}
+		for _, path := range paths {
+			if info, err := os.Stat(path); err == nil && info.IsDir() {
+				infos[path] = info
+			}
+		}
+		return jumpCheckedMsg{gen: gen, infos: infos}
+	}
+}
+
+// setJumpChecked drops the directories that do not exist anymore from the jump view
+func (thiss *Model) setJumpChecked(msg jumpCheckedMsg) {
+	if msg.gen != thiss.jumpGen || thiss.mode != modeJump {
+		return
+	}
+	thiss.jumpInfos = msg.infos
+	thiss.lsJump()
+	thiss.calculateColsAndRows()
+	thiss.fixCursor()
+}
+
+// lsJump fills the view with the directories of the database that match the search input. Until they
+// are checked, the directories are listed without details
+func (thiss *Model) lsJump() {
+	items := []Item{}
+	for _, e := range rankFrecency(thiss.jumpEntries, strings.Fields(thiss.searchInput), time.Now()) {
+		name := strings.TrimSuffix(e.path, "/") + "/"
+		if thiss.jumpInfos == nil {
+			items = append(items, Item{name: name, fileInfo: virtualDirInfo{filepath.Base(e.path)}, fullPath: e.path})
+		} else if info, found := thiss.jumpInfos[e.path]; found {
+			items = append(items, pathItem(name, e.path, info, nil))
+		}
+	}
+	thiss.items = items
+}
+
+// typeJump adds the typed runes to the jump input and ranks the directories again
+func (thiss *Model) typeJump(runes []rune) {
+	thiss.searchInput += string(runes)
+	thiss.lsJump()
+	thiss.cursorIx = 0
+	thiss.rowOffset = 0
+	thiss.calculateColsAndRows()
+}
+
+// openJumpResult goes to the focused directory of the jump view
+func (thiss *Model) openJumpResult() {
+	if len(thiss.items) == 0 {
+		return
+	}
+	path := thiss.CurrentItem().fullPath
+	thiss.changeMode(modeList)
+	thiss.goToPath(path)
+}
+
+func runQuery(args []string) int {
+	fs := flag.NewFlagSet("query", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: cd-surfer query [flags] <terms>\n\n"+
+			"Prints the visited directory with the best frecency (frequency and recency) that matches the terms.\n"+
+			"The terms must be found on the path in order, the last one on its last component. Exits with 1 if\n"+
+			"nothing matches.\n\n")
+		fs.PrintDefaults()
+	}
+	list := fs.Bool("list", false, "print all the matching directories, the best first")
+	score := fs.Bool("score", false, "print the score before each directory")
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+	now := time.Now()
+	matches, err := queryFrecency(fs.Args(), now)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if len(matches) == 0 {
+		return 1
+	}
+	if !*list {
+		matches = matches[:1]
+	}
+	for _, e := range matches {
+		if *score {
+			fmt.Printf("%8.1f ", frecencyScore(e, now))
+		}
+		fmt.Println(e.path)
+	}
+	return 0
+}
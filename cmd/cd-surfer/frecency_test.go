@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestFrecencyMatches(t *testing.T) {
+	cases := []struct {
+		terms []string
+		match bool
+	}{
+		{nil, true},
+		{[]string{"surf"}, true},
+		{[]string{"proj", "surf"}, true},
+		{[]string{"surf", "proj"}, false}, // Out of order
+		{[]string{"proj"}, false},         // Not on the last component
+		{[]string{"Surf"}, false},         // Smart-case
+		{[]string{"PROJ", "SURF"}, false},
+	}
+	for _, c := range cases {
+		if frecencyMatches("/home/me/projects/cd-surfer", c.terms) != c.match {
+			t.Error(c.terms)
+		}
+	}
+}
+
+func TestFrecencyQuery(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", filepath.Join(tmp, "data"))
+	often, recent := filepath.Join(tmp, "src", "often"), filepath.Join(tmp, "src", "recent")
+	os.MkdirAll(often, 0755)
+	os.MkdirAll(recent, 0755)
+	now := time.Now()
+	for ix := 0; ix < 10; ix++ {
+		recordVisit(often, now.Add(-30*24*time.Hour))
+	}
+	recordVisit(recent, now)
+	recordVisit(filepath.Join(tmp, "gone"), now)
+
+	matches, err := queryFrecency([]string{"src"}, now)
+	if err != nil || len(matches) != 0 { // "src" is not on the last component
+		t.Fatal(matches, err)
+	}
+	matches, _ = queryFrecency(nil, now)
+	// often: 10 / 4, recent: 1 * 4. Missing directories are skipped
+	if len(matches) != 2 || matches[0].path != recent || matches[1].rank != 10 {
+		t.Fatal(matches)
+	}
+	matches, _ = queryFrecency([]string{"src", "of"}, now)
+	if len(matches) != 1 || matches[0].path != often {
+		t.Fatal(matches)
+	}
+
+	// Aging: the ranks are scaled down when the total exceeds frecencyMaxRank
+	db, _ := loadFrecency()
+	db[0].rank = frecencyMaxRank
+	saveFrecency(db)
+	db, _ = loadFrecency()
+	if len(db) != 1 || db[0].path != often || db[0].rank >= frecencyMaxRank {
+		t.Fatal(db)
+	}
+}
+
+func TestImportFrecency(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", filepath.Join(tmp, "data"))
+	now := time.Unix(1700000000, 0)
+
+	var zo bytes.Buffer
+	binary.Write(&zo, binary.LittleEndian, uint32(3))
+	binary.Write(&zo, binary.LittleEndian, uint64(2))
+	for _, path := range []string{"/a", "/b|c"} {
+		binary.Write(&zo, binary.LittleEndian, uint64(len(path)))
+		zo.WriteString(path)
+		binary.Write(&zo, binary.LittleEndian, 2.5)
+		binary.Write(&zo, binary.LittleEndian, uint64(1600000000))
+	}
+	os.WriteFile(filepath.Join(tmp, "db.zo"), zo.Bytes(), 0644)
+	os.WriteFile(filepath.Join(tmp, "z"), []byte("/b|c|3|1650000000\ninvalid\n"), 0644)
+	os.WriteFile(filepath.Join(tmp, "autojump.txt"), []byte("10.5\t/d/\n"), 0644)
+
+	for _, name := range []string{"zoxide", "z", "autojump"} {
+		file := map[string]string{"zoxide": "db.zo", "z": "z", "autojump": "autojump.txt"}[name]
+		if _, err := importFrecency(frecencyImporters[name], filepath.Join(tmp, file), now); err != nil {
+			t.Fatal(name, err)
+		}
+	}
+	db, _ := loadFrecency()
+	expected := []frecencyEntry{{"/a", 2.5, 1600000000}, {"/b|c", 5.5, 1650000000}, {"/d", 10.5, 1700000000}}
+	if len(db) != len(expected) {
+		t.Fatal(db)
+	}
+	for ix := range expected {
+		if db[ix] != expected[ix] {
+			t.Fatal(db)
+		}
+	}
+	if _, err := parseZoxideDb(zo.Bytes()[:20], now); err == nil {
+		t.Fail()
+	}
+}
+
+func TestJumpMode(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", filepath.Join(tmp, "data"))
+	os.MkdirAll(filepath.Join(tmp, "work", "api"), 0755)
+	os.MkdirAll(filepath.Join(tmp, "docs"), 0755)
+	m := Model{path: tmp}
+	m.Init()
+	m.Update(tea.WindowSizeMsg{Width: 80, Height: 20})
+	m.goToPath(filepath.Join(tmp, "work", "api"))
+	m.goParent()
+	m.goParent()
+	m.setCursorToName("docs")
+	m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m.goToPath("/")
+
+	m.visits = append(m.visits, frecencyEntry{path: filepath.Join(tmp, "work", "gone"), rank: 1, lastVisit: time.Now().Unix()})
+
+	_, cmd := m.update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("j"), Alt: true})
+	if m.mode != modeJump || len(m.items) < 5 {
+		t.Fatal(m.mode, m.items)
+	}
+	// Missing directories are dropped once checked
+	m.Update(cmd())
+	for _, it := range m.items {
+		if it.fullPath == filepath.Join(tmp, "work", "gone") || it.details.Perm == "" {
+			t.Fatal(m.items)
+		}
+	}
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("work")})
+	m.Update(tea.KeyMsg{Type: tea.KeySpace})
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("apx")})
+	if len(m.items) != 0 {
+		t.Fatal(m.items)
+	}
+	m.Update(tea.KeyMsg{Type: tea.KeyBackspace})
+	if m.searchInput != "work ap" || len(m.items) != 1 {
+		t.Fatal(m.searchInput, m.items)
+	}
+	m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if m.mode != modeList || m.path != filepath.Join(tmp, "work", "api") {
+		t.Fatal(m.mode, m.path)
+	}
+
+	// The visits are saved on exit, keeping the ones saved by other instances
+	if _, err := os.Stat(frecencyPath()); err == nil {
+		t.Fatal("database written while browsing")
+	}
+	recordVisit(filepath.Join(tmp, "docs"), time.Now())
+	if err := m.saveVisits(); err != nil {
+		t.Fatal(err)
+	}
+	db, _ := loadFrecency()
+	ranks := map[string]float64{}
+	for _, e := range db {
+		ranks[e.path] = e.rank
+	}
+	if len(db) != 6 || ranks[filepath.Join(tmp, "docs")] != 2 || ranks[filepath.Join(tmp, "work", "api")] != 2 {
+		t.Fatal(db)
+	}
+}
+
+func TestLockFrecency(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	unlock, err := lockFrecency()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := recordVisit("/a", time.Now()); err == nil {
+		t.Fatal("database written while locked")
+	}
+	unlock()
+	if err := recordVisit("/a", time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	// Locks left by instances that crashed are removed
+	lockFrecency()
+	old := time.Now().Add(-time.Minute)
+	os.Chtimes(frecencyLockPath(), old, old)
+	if err := recordVisit("/a", time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(frecencyLockPath()); err == nil {
+		t.Fatal("lock not removed")
+	}
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andriykrefer/cdsurfer/config"
+)
+
+// Importers of the databases of other directory jumpers into the frecency database
+
+type frecencyImporter struct {
+	defaultPath func() string
+	parse       func(data []byte, now time.Time) ([]frecencyEntry, error)
+}
+
+var frecencyImporters = map[string]frecencyImporter{
+	"zoxide":   {zoxideDbPath, parseZoxideDb},
+	"z":        {zDbPath, parseZDb},
+	"autojump": {autojumpDbPath, parseAutojumpDb},
+}
+
+func zoxideDbPath() string {
+	if dir := os.Getenv("_ZO_DATA_DIR"); dir != "" {
+		return filepath.Join(dir, "db.zo")
+	}
+	return filepath.Join(config.DataHome(), "zoxide", "db.zo")
+}
+
+func zDbPath() string {
+	if path := os.Getenv("_Z_DATA"); path != "" {
+		return path
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".z")
+}
+
+func autojumpDbPath() string {
+	return filepath.Join(config.DataHome(), "autojump", "autojump.txt")
+}
+
+// parseZoxideDb parses the zoxide database (db.zo, version 3): the version as an uint32, then the
+// number of directories as an uint64 and, for each directory, the path length as an uint64, the path,
+// the rank as a float64 and the last access as an uint64 (unix time). All little endian
+func parseZoxideDb(data []byte, now time.Time) ([]frecencyEntry, error) {
+	errCorrupted := errors.New("corrupted zoxide database")
+	r := bytes.NewReader(data)
+	var version uint32
+	if err := binary.Read(r, binary.LittleEndian, &version); err != nil {
+		return nil, errCorrupted
+	}
+	if version != 3 {
+		return nil, fmt.Errorf("unsupported zoxide database version %d", version)
+	}
+	var count uint64
+	if err := binary.Read(r, binary.LittleEndian, &count); err != nil {
+		return nil, errCorrupted
+	}
+	entries := []frecencyEntry{}
+	for ix := uint64(0); ix < count; ix++ {
+		var pathSz uint64
+		if err := binary.Read(r, binary.LittleEndian, &pathSz); err != nil || pathSz > uint64(r.Len()) {
+			return nil, errCorrupted
+		}
+		path := make([]byte, pathSz)
+		r.Read(path)
+		var dir struct {
+			Rank         float64
+			LastAccessed uint64
+		}
+		if err := binary.Read(r, binary.LittleEndian, &dir); err != nil {
+			return nil, errCorrupted
+		}
+		if math.IsNaN(dir.Rank) || dir.LastAccessed > math.MaxInt64 {
+			return nil, errCorrupted
+		}
+		entries = append(entries, frecencyEntry{path: string(path), rank: dir.Rank, lastVisit: int64(dir.LastAccessed)})
+	}
+	return entries, nil
+}
+
+// parseZDb parses the z database (~/.z): "path|rank|last visit (unix time)" lines
+func parseZDb(data []byte, now time.Time) ([]frecencyEntry, error) {
+	entries := []frecencyEntry{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		// The path may have '|', so the fields are split from the end
+		timeIx := strings.LastIndex(line, "|")
+		if timeIx < 0 {
+			continue
+		}
+		rankIx := strings.LastIndex(line[:timeIx], "|")
+		if rankIx <= 0 {
+			continue
+		}
+		rank, err1 := strconv.ParseFloat(line[rankIx+1:timeIx], 64)
+		lastVisit, err2 := strconv.ParseInt(line[timeIx+1:], 10, 64)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		entries = append(entries, frecencyEntry{path: line[:rankIx], rank: rank, lastVisit: lastVisit})
+	}
+	return entries, scanner.Err()
+}
+
+// parseAutojumpDb parses the autojump database: "weight<tab>path" lines. autojump does not save the time
+// of the visits, so they are imported as visited now
+func parseAutojumpDb(data []byte, now time.Time) ([]frecencyEntry, error) {
+	entries := []frecencyEntry{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		weight, path, found := strings.Cut(scanner.Text(), "\t")
+		if !found || path == "" {
+			continue
+		}
+		rank, err := strconv.ParseFloat(weight, 64)
+		if err != nil {
+			continue
+		}
+		entries = append(entries, frecencyEntry{path: path, rank: rank, lastVisit: now.Unix()})
+	}
+	return entries, scanner.Err()
+}
+
+// importFrecency merges the database of another jumper into the frecency database. It returns the number
+// of imported directories
+func importFrecency(importer frecencyImporter, path string, now time.Time) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	entries, err := importer.parse(data, now)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", path, err)
+	}
+	for ix := range entries {
+		entries[ix].path = filepath.Clean(entries[ix].path)
+	}
+	return len(entries), addToFrecency(entries)
+}
+
+func runImport(args []string) int {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: cd-surfer import [flags] <zoxide|z|autojump>\n\n"+
+			"Adds the directories of the zoxide, z or autojump database to the frecency database (%s).\n\n",
+			frecencyPath())
+		fs.PrintDefaults()
+	}
+	file := fs.String("file", "", "database to import, instead of the default location of the jumper")
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+	importer, found := frecencyImporters[fs.Arg(0)]
+	if !found {
+		fmt.Fprintf(os.Stderr, "unknown database %q. Valid databases: zoxide, z, autojump\n", fs.Arg(0))
+		return 2
+	}
+	path := *file
+	if path == "" {
+		path = importer.defaultPath()
+	}
+	n, err := importFrecency(importer, path, time.Now())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Printf("Imported %d directories from %s\n", n, path)
+	return 0
+}
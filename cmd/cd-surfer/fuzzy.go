@@ -0,0 +1,138 @@
+package main
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// Fuzzy match scores, similar to fzf: every matched rune scores, matches on word boundaries and
+// consecutive matches score more, and gaps between matches are penalized
+const (
+	fuzzyScoreMatch       = 16
+	fuzzyBonusBoundary    = 8
+	fuzzyBonusFirstRune   = 10
+	fuzzyBonusConsecutive = 8
+	fuzzyPenaltyGapStart  = 3
+	fuzzyPenaltyGapExtend = 1
+)
+
+const fuzzyNoMatch = -1 << 30
+
+// fuzzyBonus returns the bonus for matching the rune at ix: the start of the text or of a word
+// (after a separator, a lower to upper case change or a letter to digit change)
+func fuzzyBonus(text []rune, ix int) int {
+	if ix == 0 {
+		return fuzzyBonusFirstRune
+	}
+	prev, cur := text[ix-1], text[ix]
+	switch {
+	case !unicode.IsLetter(prev) && !unicode.IsDigit(prev) && (unicode.IsLetter(cur) || unicode.IsDigit(cur)):
+		return fuzzyBonusBoundary
+	case unicode.IsLower(prev) && unicode.IsUpper(cur):
+		return fuzzyBonusBoundary
+	case unicode.IsLetter(prev) && unicode.IsDigit(cur):
+		return fuzzyBonusBoundary
+	}
+	return 0
+}
+
+// fuzzyMatch matches the runes of pattern, in order, on text. It returns the score of the best match
+// and the rune indexes of text that were matched
+func fuzzyMatch(pattern, text string, caseSensitive bool) (score int, positions []int, ok bool) {
+	p := []rune(pattern)
+	t := []rune(text)
+	if len(p) == 0 || len(p) > len(t) {
+		return 0, nil, len(p) == 0
+	}
+	folded := make([]rune, len(t))
+	copy(folded, t)
+	if !caseSensitive {
+		for ix := range p {
+			p[ix] = unicode.ToLower(p[ix])
+		}
+		for ix := range t {
+			folded[ix] = unicode.ToLower(t[ix])
+		}
+	}
+
+	// Fast path: is pattern a subsequence of text?
+	pIx := 0
+	for _, r := range folded {
+		if pIx < len(p) && r == p[pIx] {
+			pIx++
+		}
+	}
+	if pIx < len(p) {
+		return 0, nil, false
+	}
+
+	// scores[i][j] is the best score of matching p[:i+1] with p[i] on t[j]. from[i][j] is the
+	// position of p[i-1] on that match
+	scores := make([][]int, len(p))
+	from := make([][]int, len(p))
+	for i := range p {
+		scores[i] = make([]int, len(t))
+		from[i] = make([]int, len(t))
+		// Best score of p[:i] ending before j-1 (a gap before j), and its position
+		gapScore, gapFrom := fuzzyNoMatch, -1
+		for j := range t {
+			scores[i][j] = fuzzyNoMatch
+			if i > 0 && j >= 2 {
+				gapScore -= fuzzyPenaltyGapExtend
+				if s := scores[i-1][j-2] - fuzzyPenaltyGapStart; scores[i-1][j-2] > fuzzyNoMatch && s > gapScore {
+					gapScore, gapFrom = s, j-2
+				}
+			}
+			if folded[j] != p[i] {
+				continue
+			}
+			bonus := fuzzyScoreMatch + fuzzyBonus(t, j)
+			if i == 0 {
+				scores[i][j] = bonus
+				continue
+			}
+			if j >= 1 && scores[i-1][j-1] > fuzzyNoMatch {
+				scores[i][j] = scores[i-1][j-1] + bonus + fuzzyBonusConsecutive
+				from[i][j] = j - 1
+			}
+			if gapFrom >= 0 && gapScore+bonus > scores[i][j] {
+				scores[i][j] = gapScore + bonus
+				from[i][j] = gapFrom
+			}
+		}
+	}
+
+	last := len(p) - 1
+	end := -1
+	for j := range t {
+		if scores[last][j] > fuzzyNoMatch && (end < 0 || scores[last][j] > scores[last][end]) {
+			end = j
+		}
+	}
+	positions = make([]int, len(p))
+	for i, j := last, end; i >= 0; i-- {
+		positions[i] = j
+		j = from[i][j]
+	}
+	return scores[last][end], positions, true
+}
+
+// runeSpans converts sorted rune indexes of text to byte spans, in pairs of start and end indexes.
+// Adjacent runes are joined in the same span
+func runeSpans(text string, positions []int) []int {
+	spans := []int{}
+	runeIx, posIx := 0, 0
+	for byteIx, r := range text {
+		if posIx < len(positions) && positions[posIx] == runeIx {
+			end := byteIx + utf8.RuneLen(r)
+			if len(spans) > 0 && spans[len(spans)-1] == byteIx {
+				spans[len(spans)-1] = end
+			} else {
+				spans = append(spans, byteIx, end)
+			}
+			posIx++
+		}
+		runeIx++
+	}
+	return spans
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/andriykrefer/cdsurfer/config"
+)
+
+func TestFuzzyMatch(t *testing.T) {
+	_, positions, ok := fuzzyMatch("mdl", "model.go", false)
+	if !ok || !reflect.DeepEqual(positions, []int{0, 2, 4}) {
+		t.Fatal(positions)
+	}
+	if _, _, ok := fuzzyMatch("mdx", "model.go", false); ok {
+		t.Fail()
+	}
+	if _, _, ok := fuzzyMatch("Mdl", "model.go", true); ok {
+		t.Fail()
+	}
+	// Word boundaries: "fb" on foo_bar matches f and b, not the o's
+	_, positions, _ = fuzzyMatch("fb", "xfoo_bar_fb", false)
+	if !reflect.DeepEqual(positions, []int{9, 10}) {
+		t.Fatal(positions)
+	}
+	_, positions, _ = fuzzyMatch("mg", "my_image.go", false)
+	if !reflect.DeepEqual(positions, []int{0, 9}) {
+		t.Fatal(positions)
+	}
+
+	consecutive, _, _ := fuzzyMatch("mod", "model.go", false)
+	spread, _, _ := fuzzyMatch("mod", "my_old_dir", false)
+	if consecutive <= spread {
+		t.Fatal(consecutive, spread)
+	}
+	boundary, _, _ := fuzzyMatch("fb", "foo_bar", false)
+	inWord, _, _ := fuzzyMatch("fb", "foobar", false)
+	if boundary <= inWord {
+		t.Fatal(boundary, inWord)
+	}
+	camel, _, _ := fuzzyMatch("fb", "fooBar", false)
+	if camel <= inWord {
+		t.Fatal(camel, inWord)
+	}
+}
+
+func TestRuneSpans(t *testing.T) {
+	spans := runeSpans("açaí.go", []int{1, 2, 3, 5})
+	if !reflect.DeepEqual(spans, []int{1, 6, 7, 8}) {
+		t.Fatal(spans)
+	}
+}
+
+func TestFuzzyFilter(t *testing.T) {
+	tmp := t.TempDir()
+	for _, name := range []string{"madeline.txt", "model.go", "main.go", "readme.md"} {
+		os.WriteFile(filepath.Join(tmp, name), nil, 0644)
+	}
+	config.SEARCH_ALGORITHM = "fuzzy"
+	t.Cleanup(func() { config.SEARCH_ALGORITHM = "substring" })
+	m := Model{path: tmp, width: 80, height: 20}
+	m.Ls()
+	m.searchFilter("mdl")
+	if len(m.filteredItems) != 2 || m.filteredItems[0].name != "model.go" {
+		t.Fatal(m.filteredItems)
+	}
+	if !reflect.DeepEqual(m.filteredItems[0].emphasisTextIx, []int{0, 1, 2, 3, 4, 5}) {
+		t.Fatal(m.filteredItems[0].emphasisTextIx)
+	}
+}
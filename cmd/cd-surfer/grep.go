@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sync"
+	"unicode/utf8"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Content search (modeGrep): the files beneath the current directory are scanned by a pool of
+// workers, and the matching lines are streamed to the list as "path:line: snippet"
+
+const (
+	grepBinaryCheckSz = 8000    // Files with a NUL byte on the beginning are binaries, as for git
+	grepMaxLineSz     = 1 << 20 // The rest of a file is skipped after a longer line
+	grepSnippetSz     = 200
+)
+
+var grepWorkers = runtime.NumCPU()
+
+// grepRegexp compiles the search input as a regexp or as a literal text, with smart-case
+func grepRegexp(input string, isRegex bool) (*regexp.Regexp, error) {
+	expr := input
+	if !isRegex {
+		expr = regexp.QuoteMeta(input)
+	}
+	if !isCaseSensitive(input) {
+		expr = "(?i)" + expr
+	}
+	return regexp.Compile(expr)
+}
+
+// startGrep cancels the running search and starts a new content search for the search input
+func (thiss *Model) startGrep() tea.Cmd {
+	re, err := grepRegexp(thiss.searchInput, thiss.grepRegex)
+	if err != nil {
+		thiss.stopFind()
+		thiss.findGen++
+		thiss.filteredItems = []Item{}
+		thiss.items = thiss.filteredItems
+		thiss.err = fmt.Errorf("invalid regexp: %w", err)
+		return nil
+	}
+	root := thiss.path
+	return thiss.startBackgroundSearch(func(ctx context.Context, results chan<- Item) {
+		grepFiles(ctx, root, re, results)
+	})
+}
+
+// typeGrep adds the typed runes to the search input and restarts the search
+func (thiss *Model) typeGrep(runes []rune) tea.Cmd {
+	thiss.searchInput += string(runes)
+	return thiss.startGrep()
+}
+
+// grepFiles sends the lines of the files beneath root that match re, until all files are scanned or
+// ctx is canceled. Binary files are skipped. results is closed at the end
+func grepFiles(ctx context.Context, root string, re *regexp.Regexp, results chan<- Item) {
+	defer close(results)
+	type job struct {
+		fullPath, rel string
+	}
+	jobs := make(chan job)
+	var wg sync.WaitGroup
+	for ix := 0; ix < grepWorkers; ix++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				grepFile(ctx, j.fullPath, j.rel, re, results)
+			}
+		}()
+	}
+	walkTree(ctx, root, func(fullPath, rel string, e os.DirEntry) bool {
+		if !e.Type().IsRegular() {
+			return true
+		}
+		select {
+		case jobs <- job{fullPath, rel}:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	})
+	close(jobs)
+	wg.Wait()
+}
+
+// grepFile sends the lines of a file that match re, until the end of the file or ctx is canceled
+func grepFile(ctx context.Context, fullPath, rel string, re *regexp.Regexp, results chan<- Item) {
+	f, err := os.Open(fullPath)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return
+	}
+	r := bufio.NewReaderSize(f, 64*1024)
+	head, _ := r.Peek(grepBinaryCheckSz)
+	if bytes.IndexByte(head, 0) >= 0 {
+		return
+	}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), grepMaxLineSz)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		loc := re.FindIndex(scanner.Bytes())
+		if loc == nil {
+			continue
+		}
+		select {
+		case results <- grepItem(fullPath, rel, info, lineNum, scanner.Text(), loc):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// grepItem returns the list item of a matching line, named "path:line: snippet", with the match
+// emphasized. The snippet is the line without the indentation, truncated to grepSnippetSz bytes
+func grepItem(fullPath, rel string, info os.FileInfo, lineNum int, line string, loc []int) Item {
+	snippet := line
+	indent := 0
+	for indent < len(snippet) && (snippet[indent] == ' ' || snippet[indent] == '\t') {
+		indent++
+	}
+	snippet = snippet[indent:]
+	if len(snippet) > grepSnippetSz {
+		cut := grepSnippetSz
+		for cut > 0 && !utf8.RuneStart(snippet[cut]) {
+			cut--
+		}
+		snippet = snippet[:cut]
+	}
+	// Control chars would break the list. They are replaced keeping the byte indexes
+	snippetBytes := []byte(snippet)
+	for ix, b := range snippetBytes {
+		if b < ' ' || b == 0x7f {
+			snippetBytes[ix] = ' '
+		}
+	}
+	prefix := fmt.Sprintf("%s:%d: ", rel, lineNum)
+	item := Item{
+		name:     prefix + string(snippetBytes),
+		fileInfo: info,
+		fullPath: fullPath,
+		line:     lineNum,
+	}
+	start, end := loc[0]-indent, min(loc[1]-indent, len(snippet))
+	if start >= 0 && start < end {
+		item.emphasisTextIx = []int{len(prefix) + start, len(prefix) + end}
+	}
+	return item
+}
+
+// openGrepResult opens the focused result on the editor, at the matched line
+func (thiss *Model) openGrepResult() tea.Cmd {
+	if len(thiss.items) == 0 {
+		return nil
+	}
+	item := thiss.CurrentItem()
+	rel, err := filepath.Rel(thiss.path, item.fullPath)
+	if err != nil {
+		thiss.err = err
+		return nil
+	}
+	if thiss.cwdFile != "" {
+		c := exec.Command("sh", "-c", shellBash.editFileCmd(rel, item.line))
+		c.Dir = thiss.path
+		return tea.ExecProcess(c, func(err error) tea.Msg {
+			return execDoneMsg{err: err}
+		})
+	}
+	return thiss.quitWithCd(thiss.shell.cdAndRunCmd(thiss.path, thiss.shell.editFileCmd(rel, item.line)))
+}
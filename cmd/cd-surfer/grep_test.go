@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func grepNames(t *testing.T, root, input string, isRegex bool) []string {
+	re, err := grepRegexp(input, isRegex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := make(chan Item)
+	go grepFiles(context.Background(), root, re, results)
+	names := []string{}
+	for item := range results {
+		names = append(names, item.name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestGrepFiles(t *testing.T) {
+	tmp := t.TempDir()
+	os.MkdirAll(filepath.Join(tmp, "src"), 0755)
+	os.WriteFile(filepath.Join(tmp, "src", "main.go"), []byte("package main\n\n\tfunc Main() {}\n"), 0644)
+	os.WriteFile(filepath.Join(tmp, "notes.txt"), []byte("main idea\nfunc.*\n"), 0644)
+	os.WriteFile(filepath.Join(tmp, "bin"), []byte("main\x00binary"), 0644)
+
+	names := grepNames(t, tmp, "main", false)
+	expected := []string{"notes.txt:1: main idea", "src/main.go:1: package main", "src/main.go:3: func Main() {}"}
+	if strings.Join(names, "|") != strings.Join(expected, "|") {
+		t.Fatal(names)
+	}
+	if names := grepNames(t, tmp, "Main", false); len(names) != 1 {
+		t.Fatal(names)
+	}
+	if names := grepNames(t, tmp, "func.*", false); len(names) != 1 || names[0] != "notes.txt:2: func.*" {
+		t.Fatal(names)
+	}
+	if names := grepNames(t, tmp, "^func .*{", true); len(names) != 0 {
+		t.Fatal(names) // The line is indented
+	}
+	if names := grepNames(t, tmp, `func \w+\(`, true); len(names) != 1 {
+		t.Fatal(names)
+	}
+	if _, err := grepRegexp("(", true); err == nil {
+		t.Fail()
+	}
+}
+
+func TestGrepItem(t *testing.T) {
+	line := "\t\tx := \"needle\"\x1b" + strings.Repeat("a", grepSnippetSz)
+	item := grepItem("/r/a.go", "a.go", nil, 7, line, []int{8, 14})
+	prefix := "a.go:7: "
+	if !strings.HasPrefix(item.name, prefix+"x := \"needle\" aaa") || len(item.name) != len(prefix)+grepSnippetSz {
+		t.Fatal(item.name)
+	}
+	if item.line != 7 || item.name[item.emphasisTextIx[0]:item.emphasisTextIx[1]] != "needle" {
+		t.Fatal(item.emphasisTextIx)
+	}
+}
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/andriykrefer/cdsurfer/term"
+)
+
+// Syntax highlighting of the text preview. The lexers are simple: they know the comments, strings,
+// numbers and keywords of each language, which is enough to make the preview readable
+
+const previewHighlightSz = 32 * 1024 // Bytes highlighted. The rest of the preview is plain text
+
+type tokenKind int
+
+const (
+	tokPlain tokenKind = iota
+	tokComment
+	tokString
+	tokNumber
+	tokKeyword
+	tokType
+	tokKey
+	tokVariable
+)
+
+var tokenColors = map[tokenKind]func(s string, isBg bool) string{
+	tokComment:  term.Gray,
+	tokString:   term.Green,
+	tokNumber:   term.Orange,
+	tokKeyword:  term.Violet,
+	tokType:     term.Cyan,
+	tokKey:      term.Blue,
+	tokVariable: term.Yellow,
+}
+
+type token struct {
+	text string
+	kind tokenKind
+}
+
+type language struct {
+	lineComment    string
+	blockComment   [2]string // Start and end
+	quotes         string    // Chars that start single line strings
+	multiLineQuote byte      // Char that starts and ends strings spanning many lines, like Go raw strings
+	keywords       map[string]bool
+	types          map[string]bool
+	variables      bool // $VAR, ${VAR} and $1
+	keys           bool // YAML keys, like "key:"
+}
+
+func wordSet(words string) map[string]bool {
+	set := map[string]bool{}
+	for _, w := range strings.Fields(words) {
+		set[w] = true
+	}
+	return set
+}
+
+var langGo = &language{
+	lineComment:    "//",
+	blockComment:   [2]string{"/*", "*/"},
+	quotes:         `"'`,
+	multiLineQuote: '`',
+	keywords: wordSet(`break case chan const continue default defer else fallthrough for func go goto if
+		import interface map package range return select struct switch type var`),
+	types: wordSet(`any bool byte comparable complex64 complex128 error float32 float64 int int8 int16 int32
+		int64 rune string uint uint8 uint16 uint32 uint64 uintptr true false iota nil append cap clear
+		close complex copy delete imag len make max min new panic print println real recover`),
+}
+
+var langYaml = &language{
+	lineComment: "#",
+	quotes:      `"'`,
+	keywords:    wordSet(`true false True False TRUE FALSE yes no on off null Null NULL ~`),
+	keys:        true,
+}
+
+var langShell = &language{
+	lineComment: "#",
+	quotes:      `"'`,
+	keywords: wordSet(`if then else elif fi for while until do done case esac in function select return
+		local export readonly declare typeset unset shift exit break continue`),
+	types: wordSet(`alias bg cd command echo eval exec fg getopts hash jobs kill printf pwd read set source
+		test trap type ulimit umask wait`),
+	variables: true,
+}
+
+var langByExt = map[string]*language{
+	".go":   langGo,
+	".yaml": langYaml,
+	".yml":  langYaml,
+	".sh":   langShell,
+	".bash": langShell,
+	".zsh":  langShell,
+	".ksh":  langShell,
+}
+
+var langByName = map[string]*language{
+	".bashrc":       langShell,
+	".bash_profile": langShell,
+	".bash_aliases": langShell,
+	".profile":      langShell,
+	".zshrc":        langShell,
+	".zprofile":     langShell,
+}
+
+var shellInterpreters = wordSet(`sh bash zsh ksh dash ash`)
+
+// detectLanguage returns the language of a file by its extension or shebang, or nil if unknown
+func detectLanguage(path string, data []byte) *language {
+	name := filepath.Base(path)
+	if lang, found := langByName[name]; found {
+		return lang
+	}
+	if lang, found := langByExt[strings.ToLower(filepath.Ext(name))]; found {
+		return lang
+	}
+	firstLine, _, _ := strings.Cut(string(data[:min(len(data), 256)]), "\n")
+	if !strings.HasPrefix(firstLine, "#!") {
+		return nil
+	}
+	fields := strings.Fields(firstLine[2:])
+	if len(fields) == 0 {
+		return nil
+	}
+	interpreter := filepath.Base(fields[0])
+	if interpreter == "env" {
+		fields = fields[1:]
+		for len(fields) > 0 && strings.HasPrefix(fields[0], "-") {
+			fields = fields[1:]
+		}
+		if len(fields) == 0 {
+			return nil
+		}
+		interpreter = filepath.Base(fields[0])
+	}
+	if shellInterpreters[interpreter] {
+		return langShell
+	}
+	return nil
+}
+
+// lexer splits the lines of a file in tokens. The lines must be passed in order, since comments and
+// strings may span many lines
+type lexer struct {
+	lang     *language
+	closing  string // End of the comment or string that continues on the next line
+	openKind tokenKind
+}
+
+// yamlKeyRe matches the indentation, list dashes, key and colon of a YAML line
+var yamlKeyRe = regexp.MustCompile(`^(\s*(?:-\s+)*)("[^"]*"|'[^']*'|[^\s#'"\-][^:#]*?|-[^\s:#][^:#]*?)(\s*:)(?:\s|$)`)
+
+func isWordByte(c byte) bool {
+	return c == '_' || c >= 0x80 || (c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// tokenize returns the tokens of the next line
+func (thiss *lexer) tokenize(line string) []token {
+	tokens := []token{}
+	add := func(text string, kind tokenKind) {
+		if text == "" {
+			return
+		}
+		if len(tokens) > 0 && tokens[len(tokens)-1].kind == kind {
+			tokens[len(tokens)-1].text += text
+			return
+		}
+		tokens = append(tokens, token{text, kind})
+	}
+	lang := thiss.lang
+	ix := 0
+	if thiss.closing != "" {
+		end := strings.Index(line, thiss.closing)
+		if end < 0 {
+			add(line, thiss.openKind)
+			return tokens
+		}
+		ix = end + len(thiss.closing)
+		add(line[:ix], thiss.openKind)
+		thiss.closing = ""
+	} else if lang.keys {
+		if m := yamlKeyRe.FindStringSubmatchIndex(line); m != nil {
+			add(line[:m[3]], tokPlain)
+			add(line[m[4]:m[5]], tokKey)
+			add(line[m[6]:m[7]], tokPlain)
+			ix = m[7]
+		}
+	}
+
+	// open adds the comment or string that starts at ix, ending with closing, and returns its length
+	open := func(rest string, startLen int, closing string, kind tokenKind) int {
+		end := strings.Index(rest[startLen:], closing)
+		if end < 0 {
+			add(rest, kind)
+			thiss.closing, thiss.openKind = closing, kind
+			return len(rest)
+		}
+		n := startLen + end + len(closing)
+		add(rest[:n], kind)
+		return n
+	}
+	for ix < len(line) {
+		c, rest := line[ix], line[ix:]
+		wordStart := ix == 0 || !isWordByte(line[ix-1])
+		switch {
+		case lang.lineComment != "" && strings.HasPrefix(rest, lang.lineComment) &&
+			(lang.lineComment != "#" || ix == 0 || line[ix-1] == ' '):
+			add(rest, tokComment)
+			return tokens
+		case lang.blockComment[0] != "" && strings.HasPrefix(rest, lang.blockComment[0]):
+			ix += open(rest, len(lang.blockComment[0]), lang.blockComment[1], tokComment)
+		case lang.multiLineQuote != 0 && c == lang.multiLineQuote:
+			ix += open(rest, 1, string(c), tokString)
+		case strings.IndexByte(lang.quotes, c) >= 0 && (!lang.keys || wordStart): // YAML: quotes inside words, like it's, are plain
+			n := quotedLen(rest, lang != langShell || c == '"')
+			add(rest[:n], tokString)
+			ix += n
+		case lang.variables && c == '$' && variableLen(rest) > 1:
+			n := variableLen(rest)
+			add(rest[:n], tokVariable)
+			ix += n
+		case isWordByte(c) && wordStart:
+			n := 1
+			for n < len(rest) && (isWordByte(rest[n]) || (isDigit(c) && rest[n] == '.')) {
+				n++
+			}
+			word := rest[:n]
+			switch {
+			case isDigit(c):
+				add(word, tokNumber)
+			case lang.keywords[word]:
+				add(word, tokKeyword)
+			case lang.types[word]:
+				add(word, tokType)
+			default:
+				add(word, tokPlain)
+			}
+			ix += n
+		case lang.keywords[string(c)] && wordStart && (ix+1 == len(line) || !isWordByte(line[ix+1])):
+			add(string(c), tokKeyword) // YAML ~
+			ix++
+		default:
+			add(string(c), tokPlain)
+			ix++
+		}
+	}
+	return tokens
+}
+
+// quotedLen returns the length of the string at the start of s, up to the closing quote or to the end
+// of s. Backslash escapes are skipped if escapes is true
+func quotedLen(s string, escapes bool) int {
+	for ix := 1; ix < len(s); ix++ {
+		switch {
+		case escapes && s[ix] == '\\':
+			ix++
+		case s[ix] == s[0]:
+			return ix + 1
+		}
+	}
+	return len(s)
+}
+
+// variableLen returns the length of the shell variable at the start of s. It returns 1 if s starts
+// with a lone $
+func variableLen(s string) int {
+	if len(s) < 2 {
+		return 1
+	}
+	switch {
+	case s[1] == '{':
+		if end := strings.IndexByte(s, '}'); end > 0 {
+			return end + 1
+		}
+		return len(s)
+	case strings.IndexByte("@*#?$!-", s[1]) >= 0 || isDigit(s[1]):
+		return 2
+	}
+	n := 1
+	for n < len(s) && isWordByte(s[n]) {
+		n++
+	}
+	return n
+}
+
+// renderTokens returns the colored tokens, cut to width runes
+func renderTokens(tokens []token, width int) string {
+	var b strings.Builder
+	for _, t := range tokens {
+		if width <= 0 {
+			break
+		}
+		text := truncate(t.text, width)
+		width -= utf8.RuneCountInString(text)
+		if colorize, found := tokenColors[t.kind]; found {
+			text = colorize(text, false)
+		}
+		b.WriteString(text)
+	}
+	return b.String()
+}
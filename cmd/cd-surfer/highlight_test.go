@@ -0,0 +1,89 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gookit/color"
+)
+
+func TestDetectLanguage(t *testing.T) {
+	cases := []struct {
+		path, content string
+		lang          *language
+	}{
+		{"main.go", "", langGo},
+		{"ci.YML", "", langYaml},
+		{".bashrc", "", langShell},
+		{"run", "#!/bin/sh\necho", langShell},
+		{"run", "#!/usr/bin/env -S bash -e\n", langShell},
+		{"run", "#!/usr/bin/python3\n", nil},
+		{"notes.txt", "text", nil},
+	}
+	for _, c := range cases {
+		if lang := detectLanguage(c.path, []byte(c.content)); lang != c.lang {
+			t.Error(c.path, c.content)
+		}
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	lex := lexer{lang: langGo}
+	tokens := lex.tokenize(`func f(s string) int { return 42 } /* multi`)
+	expected := []token{
+		{"func", tokKeyword}, {" f(s ", tokPlain}, {"string", tokType}, {") ", tokPlain}, {"int", tokType},
+		{" { ", tokPlain}, {"return", tokKeyword}, {" ", tokPlain}, {"42", tokNumber}, {" } ", tokPlain},
+		{"/* multi", tokComment},
+	}
+	if !reflect.DeepEqual(tokens, expected) {
+		t.Fatal(tokens)
+	}
+	tokens = lex.tokenize(`line */ s := "a\"b" // c`)
+	expected = []token{
+		{"line */", tokComment}, {" s := ", tokPlain}, {`"a\"b"`, tokString}, {" ", tokPlain}, {"// c", tokComment},
+	}
+	if !reflect.DeepEqual(tokens, expected) {
+		t.Fatal(tokens)
+	}
+
+	lex = lexer{lang: langYaml}
+	tokens = lex.tokenize(`  - name: it's true # comment`)
+	expected = []token{
+		{"  - ", tokPlain}, {"name", tokKey}, {": it's ", tokPlain}, {"true", tokKeyword}, {" ", tokPlain},
+		{"# comment", tokComment},
+	}
+	if !reflect.DeepEqual(tokens, expected) {
+		t.Fatal(tokens)
+	}
+
+	lex = lexer{lang: langShell}
+	tokens = lex.tokenize(`echo "$HOME" ${1}#x '\' $#`)
+	expected = []token{
+		{"echo", tokType}, {" ", tokPlain}, {`"$HOME"`, tokString}, {" ", tokPlain}, {"${1}", tokVariable},
+		{"#x ", tokPlain}, {`'\'`, tokString}, {" ", tokPlain}, {"$#", tokVariable},
+	}
+	if !reflect.DeepEqual(tokens, expected) {
+		t.Fatal(tokens)
+	}
+}
+
+func TestRenderTokens(t *testing.T) {
+	tokens := []token{{"if", tokKeyword}, {" x == ", tokPlain}, {"10", tokNumber}}
+	out := renderTokens(tokens, 8)
+	if color.ClearCode(out) != "if x == " || !strings.Contains(out, "\x1b[") {
+		t.Fatalf("%q", out)
+	}
+}
+
+func TestPreviewHighlight(t *testing.T) {
+	data := []byte("a := `raw\nstill raw\n` + b\n")
+	lines := previewText(data, langGo, 2, 80, 10)
+	if len(lines) != 2 || color.ClearCode(lines[0]) != "still raw" {
+		t.Fatalf("%q", lines)
+	}
+	plain := previewText(data, nil, 2, 80, 10)
+	if plain[0] != "still raw" {
+		t.Fatalf("%q", plain)
+	}
+}
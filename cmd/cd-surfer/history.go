@@ -0,0 +1,111 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// Back and forward navigation history, like on web browsers. Each entry keeps the focused item and the
+// scroll of the directory, so they are restored when going back to it
+
+const historyMaxSz = 100
+
+type historyEntry struct {
+	path       string
+	cursorName string // Name of the focused item ("" if none)
+	rowOffset  int
+}
+
+// currentHistoryEntry returns the entry of the current directory, with its focused item. Views other
+// than the list use the remembered cursor of the directory
+func (thiss *Model) currentHistoryEntry() historyEntry {
+	if thiss.mode != modeList && thiss.mode != modeSearch {
+		c := thiss.cursors[filepath.Clean(thiss.path)]
+		return historyEntry{path: thiss.path, cursorName: c.Name, rowOffset: c.RowOffset}
+	}
+	entry := historyEntry{path: thiss.path, rowOffset: thiss.rowOffset}
+	if thiss.cursorIx < len(thiss.items) && thiss.CurrentItem().fileInfo != nil {
+		entry.cursorName = thiss.CurrentItem().fileInfo.Name()
+	}
+	return entry
+}
+
+// pushHistory adds the directory that was left to the back history. Going to a new directory clears
+// the forward history
+func (thiss *Model) pushHistory(from historyEntry) {
+	if filepath.Clean(from.path) == filepath.Clean(thiss.path) {
+		return
+	}
+	thiss.backHistory = append(thiss.backHistory, from)
+	if len(thiss.backHistory) > historyMaxSz {
+		thiss.backHistory = thiss.backHistory[len(thiss.backHistory)-historyMaxSz:]
+	}
+	thiss.forwardHistory = nil
+}
+
+// restoreHistoryEntry goes to the directory of entry, restoring its focused item and scroll
+func (thiss *Model) restoreHistoryEntry(entry historyEntry) bool {
+	from := thiss.path
+	if !thiss.loadDir(entry.path) {
+		return false
+	}
+	thiss.previousPath = from
+	thiss.changeMode(modeList)
+	thiss.setCursor(entry.cursorName, 0, entry.rowOffset)
+	thiss.recordCurrentDir()
+	return true
+}
+
+// goBack goes n entries back on the history. The skipped entries can be reached going forward. Entries
+// of directories that cannot be opened anymore are dropped
+func (thiss *Model) goBack(n int) {
+	if n <= 0 || n > len(thiss.backHistory) {
+		return
+	}
+	current := thiss.currentHistoryEntry()
+	targetIx := len(thiss.backHistory) - n
+	if !thiss.restoreHistoryEntry(thiss.backHistory[targetIx]) {
+		thiss.backHistory = append(thiss.backHistory[:targetIx], thiss.backHistory[targetIx+1:]...)
+		thiss.refresh()
+		return
+	}
+	thiss.forwardHistory = append(thiss.forwardHistory, current)
+	for ix := len(thiss.backHistory) - 1; ix > targetIx; ix-- {
+		thiss.forwardHistory = append(thiss.forwardHistory, thiss.backHistory[ix])
+	}
+	thiss.backHistory = thiss.backHistory[:targetIx]
+}
+
+// goForward goes to the next entry of the forward history
+func (thiss *Model) goForward() {
+	if len(thiss.forwardHistory) == 0 {
+		return
+	}
+	current := thiss.currentHistoryEntry()
+	lastIx := len(thiss.forwardHistory) - 1
+	target := thiss.forwardHistory[lastIx]
+	thiss.forwardHistory = thiss.forwardHistory[:lastIx]
+	if !thiss.restoreHistoryEntry(target) {
+		return
+	}
+	thiss.backHistory = append(thiss.backHistory, current)
+}
+
+// lsHistory fills the view with the back history, the most recent first, named like
+// "/home/me/project  (main.go)"
+func (thiss *Model) lsHistory() {
+	items := []Item{}
+	for ix := len(thiss.backHistory) - 1; ix >= 0; ix-- {
+		entry := thiss.backHistory[ix]
+		name := entry.path
+		if entry.cursorName != "" {
+			name += "  (" + entry.cursorName + ")"
+		}
+		info, err := os.Stat(entry.path)
+		if err != nil {
+			info = virtualDirInfo{filepath.Base(entry.path)}
+		}
+		items = append(items, pathItem(name, entry.path, info, err))
+	}
+	thiss.items = items
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestHistory(t *testing.T) {
+	tmp := t.TempDir()
+	for _, dir := range []string{"a", "b", "c"} {
+		os.Mkdir(filepath.Join(tmp, dir), 0755)
+	}
+	for ix := 0; ix < 40; ix++ {
+		os.WriteFile(filepath.Join(tmp, "a", fmt.Sprintf("file%02d", ix)), nil, 0644)
+	}
+	m := Model{path: filepath.Join(tmp, "a")}
+	m.Init()
+	m.Update(tea.WindowSizeMsg{Width: 20, Height: 10})
+	m.setCursorToName("file30")
+	offset := m.rowOffset
+	m.goToPath(filepath.Join(tmp, "b"))
+	m.goParent()
+	m.setCursorToName("c")
+	m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if len(m.backHistory) != 3 {
+		t.Fatal(m.backHistory)
+	}
+
+	// Back to a: the cursor and the scroll are restored
+	m.Update(tea.KeyMsg{Type: tea.KeyLeft, Alt: true})
+	m.Update(tea.KeyMsg{Type: tea.KeyLeft, Alt: true})
+	m.Update(tea.KeyMsg{Type: tea.KeyLeft, Alt: true})
+	if m.path != filepath.Join(tmp, "a") || m.CurrentItem().name != "file30" || m.rowOffset != offset || offset == 0 {
+		t.Fatal(m.path, m.CurrentItem().name, m.rowOffset)
+	}
+	m.Update(tea.KeyMsg{Type: tea.KeyRight, Alt: true})
+	if m.path != filepath.Join(tmp, "b") || len(m.backHistory) != 1 || len(m.forwardHistory) != 2 {
+		t.Fatal(m.path, m.backHistory, m.forwardHistory)
+	}
+	m.Update(tea.KeyMsg{Type: tea.KeyRight, Alt: true})
+	m.Update(tea.KeyMsg{Type: tea.KeyRight, Alt: true})
+	if m.path != filepath.Join(tmp, "c") || len(m.forwardHistory) != 0 {
+		t.Fatal(m.path, m.forwardHistory)
+	}
+
+	// History view, the most recent first. Going to an entry keeps the newer ones on the forward history
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("y"), Alt: true})
+	if m.mode != modeHistory || len(m.items) != 3 || m.items[0].name != tmp+"  (c)" || m.items[2].fullPath != filepath.Join(tmp, "a") {
+		t.Fatal(m.mode, m.items)
+	}
+	m.Update(tea.KeyMsg{Type: tea.KeyDown})
+	m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if m.mode != modeList || m.path != filepath.Join(tmp, "b") || len(m.backHistory) != 1 || len(m.forwardHistory) != 2 {
+		t.Fatal(m.mode, m.path, m.backHistory, m.forwardHistory)
+	}
+	// A new directory clears the forward history
+	m.goToPath(tmp)
+	if len(m.forwardHistory) != 0 || len(m.backHistory) != 2 {
+		t.Fatal(m.backHistory, m.forwardHistory)
+	}
+
+	// Deleted directories are dropped
+	os.Remove(filepath.Join(tmp, "b"))
+	m.Update(tea.KeyMsg{Type: tea.KeyLeft, Alt: true})
+	if m.err == nil || m.path != tmp || len(m.backHistory) != 1 {
+		t.Fatal(m.err, m.path, m.backHistory)
+	}
+}
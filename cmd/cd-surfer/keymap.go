@@ -0,0 +1,240 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// keyAction is an action that can be remapped on the [keymap] table of the config file
+type keyAction struct {
+	name    string
+	binding *key.Binding
+}
+
+var keyActions = []keyAction{
+	{"quit", &keyQuit},
+	{"quit_without_cd", &keyQuitWithoutCd},
+	{"up", &keyUp},
+	{"down", &keyDown},
+	{"left", &keyLeft},
+	{"right", &keyRight},
+	{"page_up", &keyPageUp},
+	{"page_down", &keyPageDown},
+	{"first", &keyHome},
+	{"last", &keyEnd},
+	{"open", &keyOpen},
+	{"parent", &keyParent},
+	{"root", &keyRoot},
+	{"enter_path", &keyEnterPath},
+	{"home", &keyTilde},
+	{"previous", &keyPrev},
+	{"back", &keyBack},
+	{"forward", &keyForward},
+	{"history_view", &keyHistoryView},
+	{"search", &keySearch},
+	{"find", &keyFind},
+	{"grep", &keyGrep},
+	{"grep_regexp", &keyGrepRegex},
+	{"select", &keySelect},
+	{"select_all", &keySelectAll},
+	{"invert_selection", &keyInvertSel},
+	{"clear_selection", &keyClearSel},
+	{"copy", &keyCopy},
+	{"cut", &keyCut},
+	{"paste", &keyPaste},
+	{"trash", &keyTrash},
+	{"delete", &keyDelete},
+	{"trash_view", &keyTrashView},
+	{"rename", &keyRename},
+	{"bulk_rename", &keyBulkRename},
+	{"new_folder", &keyNewFolder},
+	{"new_file", &keyNewFile},
+	{"details", &keyDetails},
+	{"preview", &keyPreview},
+	{"extract", &keyExtract},
+	{"compress", &keyCompress},
+	{"bookmark", &keyMark},
+	{"jump_to_bookmark", &keyJump},
+	{"bookmarks_view", &keyBookmarksView},
+	{"frecent_jump", &keyFrecentJump},
+}
+
+// reservedKeys are used by the search and the prompts, and cannot be bound to actions
+var reservedKeys = []string{"esc", "backspace", "ctrl+u"}
+
+// keyPresets change the default bindings of some actions
+var keyPresets = map[string]map[string][]string{
+	"default": {},
+	"vim": {
+		"up":               {"k", "up"},
+		"down":             {"j", "down"},
+		"left":             {"h", "left"},
+		"right":            {"l", "right"},
+		"page_up":          {"ctrl+b", "pgup"},
+		"page_down":        {"ctrl+f", "pgdown"},
+		"first":            {"g", "home"},
+		"last":             {"G", "end"},
+		"search":           {"/"},
+		"root":             {"\\"},
+		"enter_path":       {":", "ctrl+l"},
+		"copy":             {"y"},
+		"cut":              {"x"},
+		"paste":            {"p"},
+		"bookmark":         {"m"},
+		"jump_to_bookmark": {"'"},
+		"back":             {"H", "alt+left"},
+		"forward":          {"L", "alt+right"},
+		"bookmarks_view":   {"M"},
+	},
+	"emacs": {
+		"up":              {"ctrl+p", "up"},
+		"down":            {"ctrl+n", "down"},
+		"left":            {"ctrl+b", "left"},
+		"right":           {"ctrl+f", "right"},
+		"page_up":         {"alt+v", "pgup"},
+		"page_down":       {"ctrl+v", "pgdown"},
+		"first":           {"alt+<", "home"},
+		"last":            {"alt+>", "end"},
+		"search":          {"ctrl+s"},
+		"copy":            {"alt+w"},
+		"cut":             {"ctrl+w"},
+		"paste":           {"ctrl+y"},
+		"quit_without_cd": {"ctrl+g", "ctrl+c"},
+	},
+}
+
+var defaultKeys = map[string][]string{}
+
+// keyNames are the names of the special keys, like "enter" or "ctrl+a"
+var keyNames = map[string]bool{}
+
+func init() {
+	for _, a := range keyActions {
+		defaultKeys[a.name] = a.binding.Keys()
+	}
+	for t := tea.KeyF20; t <= 127; t++ {
+		if name := (tea.Key{Type: t}).String(); name != "" && t != tea.KeyRunes {
+			keyNames[name] = true
+		}
+	}
+}
+
+func presetNames() string {
+	names := []string{}
+	for name := range keyPresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+func actionNames() string {
+	names := []string{}
+	for _, a := range keyActions {
+		names = append(names, a.name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// normalizeKey validates a key of the config file and returns it as bubbletea names it. "space" is
+// accepted for " "
+func normalizeKey(k string) (string, error) {
+	name := strings.TrimPrefix(k, "alt+")
+	if name == "space" {
+		name = " "
+	}
+	if !keyNames[name] && utf8.RuneCountInString(name) != 1 {
+		return "", fmt.Errorf("unknown key %q", k)
+	}
+	if strings.HasPrefix(k, "alt+") {
+		return "alt+" + name, nil
+	}
+	return name, nil
+}
+
+// applyKeymap sets the bindings of the preset, then the overrides (action name -> keys). It fails on
+// unknown actions or keys, and when a key is bound to more than one action
+func applyKeymap(preset string, overrides map[string][]string) error {
+	presetKeys, found := keyPresets[preset]
+	if !found {
+		return fmt.Errorf("unknown keymap preset %q. Valid presets: %s", preset, presetNames())
+	}
+	for action := range overrides {
+		if _, found := defaultKeys[action]; !found {
+			return fmt.Errorf("keymap: unknown action %q. Valid actions: %s", action, actionNames())
+		}
+	}
+
+	boundTo := map[string]string{}
+	for _, k := range reservedKeys {
+		boundTo[k] = "(reserved)"
+	}
+	bindings := map[string][]string{}
+	for _, a := range keyActions {
+		keys, found := overrides[a.name]
+		if !found {
+			keys, found = presetKeys[a.name]
+		}
+		if !found {
+			keys = defaultKeys[a.name]
+		}
+		for _, k := range keys {
+			k, err := normalizeKey(k)
+			if err != nil {
+				return fmt.Errorf("keymap.%s: %w", a.name, err)
+			}
+			if other, found := boundTo[k]; found && other != a.name {
+				return fmt.Errorf("keymap: key %q is bound to both %s and %s", k, other, a.name)
+			}
+			boundTo[k] = a.name
+			bindings[a.name] = append(bindings[a.name], k)
+		}
+	}
+	for _, a := range keyActions {
+		a.binding.SetKeys(bindings[a.name]...)
+	}
+	return nil
+}
+
+// dumpKeymap returns the effective bindings, in the config file format
+func dumpKeymap() string {
+	out := "\n[keymap]\n"
+	for _, a := range keyActions {
+		keys := []string{}
+		for _, k := range a.binding.Keys() {
+			keys = append(keys, strconv.Quote(k))
+		}
+		out += fmt.Sprintf("%s = [%s]\n", a.name, strings.Join(keys, ", "))
+	}
+	return out
+}
+
+// keyHint returns the first key of a binding and its description, like "[alt+d] Details". It returns
+// "" for unbound actions
+func keyHint(b key.Binding, desc string) string {
+	if len(b.Keys()) == 0 {
+		return ""
+	}
+	k := b.Keys()[0]
+	if k == " " {
+		k = "space"
+	}
+	return "[" + k + "] " + desc
+}
+
+// joinHints joins the non empty hints
+func joinHints(hints ...string) string {
+	out := []string{}
+	for _, h := range hints {
+		if h != "" {
+			out = append(out, h)
+		}
+	}
+	return strings.Join(out, "   ")
+}
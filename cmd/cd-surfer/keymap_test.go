@@ -0,0 +1,66 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestApplyKeymap(t *testing.T) {
+	t.Cleanup(func() { applyKeymap("default", nil) })
+
+	for preset := range keyPresets {
+		if err := applyKeymap(preset, nil); err != nil {
+			t.Error(preset, err)
+		}
+	}
+
+	err := applyKeymap("vim", map[string][]string{"quit": {"alt+enter", "Q"}, "details": {"alt+space"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	j := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("j")}
+	if !key.Matches(j, keyDown) || !key.Matches(tea.KeyMsg{Type: tea.KeyDown}, keyDown) {
+		t.Fail()
+	}
+	if !key.Matches(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("Q")}, keyQuit) ||
+		key.Matches(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("q"), Alt: true}, keyQuit) {
+		t.Fail()
+	}
+	if !key.Matches(tea.KeyMsg{Type: tea.KeySpace, Runes: []rune(" "), Alt: true}, keyDetails) {
+		t.Fail()
+	}
+
+	// Starts from the defaults again
+	if err := applyKeymap("default", map[string][]string{"search": {}}); err != nil {
+		t.Fatal(err)
+	}
+	if key.Matches(j, keyDown) || key.Matches(j, keySearch) {
+		t.Fail()
+	}
+}
+
+func TestApplyKeymapErrors(t *testing.T) {
+	t.Cleanup(func() { applyKeymap("default", nil) })
+
+	cases := []struct {
+		preset    string
+		overrides map[string][]string
+	}{
+		{"nano", nil},
+		{"default", map[string][]string{"fly": {"f"}}},
+		{"default", map[string][]string{"quit": {"ctrl+enter"}}},
+		{"default", map[string][]string{"quit": {"qq"}}},
+		{"default", map[string][]string{"quit": {"alt+"}}},
+		{"default", map[string][]string{"quit": {"esc"}}},
+		{"default", map[string][]string{"copy": {"x"}, "cut": {"x"}}},
+		{"default", map[string][]string{"details": {"alt+x"}}}, // Cut
+		{"vim", map[string][]string{"root": {"k"}}},            // Up
+	}
+	for _, c := range cases {
+		if err := applyKeymap(c.preset, c.overrides); err == nil {
+			t.Error(c.preset, c.overrides)
+		}
+	}
+}
@@ -1,16 +1,109 @@
 package main
 
 import (
+	"flag"
+	"fmt"
 	"os"
 
+	"github.com/andriykrefer/cdsurfer/config"
 	tea "github.com/charmbracelet/bubbletea"
 )
 
 func main() {
 	// runtime.Breakpoint()
-	p := tea.NewProgram(&Model{}, tea.WithOutput(os.Stderr))
+	args := os.Args[1:]
+	if len(args) > 0 {
+		switch args[0] {
+		case "pick":
+			os.Exit(runPick(args[1:]))
+		case "init":
+			os.Exit(runInit(args[1:]))
+		case "config":
+			os.Exit(runConfig(args[1:]))
+		case "query":
+			os.Exit(runQuery(args[1:]))
+		case "import":
+			os.Exit(runImport(args[1:]))
+		}
+	}
+	os.Exit(runBrowse(args))
+}
+
+func runBrowse(args []string) int {
+	fs := flag.NewFlagSet("cd-surfer", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: cd-surfer [flags]\n"+
+			"       cd-surfer init [--cwd-file] <shell>\n"+
+			"       cd-surfer pick [flags] [dir]\n"+
+			"       cd-surfer config dump [flags]\n"+
+			"       cd-surfer query [--list] [--score] <terms>\n"+
+			"       cd-surfer import [--file <path>] <zoxide|z|autojump>\n\n"+
+			"Outputs the cd command to be evaluated by the shell wrapper function (see cd-surfer init).\n\n")
+		fs.PrintDefaults()
+	}
+	shellName := fs.String("shell", "bash", "syntax of the emitted commands: bash, zsh, fish, nu or pwsh")
+	cwdFile := fs.String("cwd-file", "", "on exit, write the final directory to this file instead of outputting a cd command")
+	config.RegisterFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+	if err := loadConfig(fs); err != nil {
+		fmt.Fprintln(os.Stderr, "config:", err)
+		return 2
+	}
+	sh, err := shellByName(*shellName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	m := &Model{shell: sh, cwdFile: *cwdFile}
+	p := tea.NewProgram(m, tea.WithOutput(os.Stderr))
 	if _, err := p.Run(); err != nil {
 		panic(err)
 	}
-	os.Exit(0)
+	if err := m.saveCursors(); err != nil {
+		fmt.Fprintln(os.Stderr, "save_cursors:", err)
+	}
+	if err := m.saveVisits(); err != nil {
+		fmt.Fprintln(os.Stderr, "frecency:", err)
+	}
+	return m.exitCode
+}
+
+func runConfig(args []string) int {
+	fs := flag.NewFlagSet("config", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: cd-surfer config dump [flags]\n\n"+
+			"Prints the effective options: %s, overridden by CDSURFER_<OPTION> environment variables\n"+
+			"and by the flags below.\n\n", config.Path())
+		fs.PrintDefaults()
+	}
+	config.RegisterFlags(fs)
+	if len(args) == 0 || args[0] != "dump" {
+		fs.Usage()
+		return 2
+	}
+	if err := fs.Parse(args[1:]); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+	if err := loadConfig(fs); err != nil {
+		fmt.Fprintln(os.Stderr, "config:", err)
+		return 2
+	}
+	fmt.Print(config.Dump() + dumpKeymap())
+	return 0
+}
+
+// loadConfig loads the options (see config.Load) and applies the keymap
+func loadConfig(fs *flag.FlagSet) error {
+	if err := config.Load(fs); err != nil {
+		return err
+	}
+	return applyKeymap(config.KEYMAP_PRESET, config.KEYMAP)
 }
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/user"
@@ -9,6 +11,7 @@ import (
 	"strconv"
 	"strings"
 	"syscall"
+	"time"
 	"unicode"
 
 	"github.com/andriykrefer/cdsurfer/config"
@@ -18,6 +21,7 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/dustin/go-humanize"
+	"golang.org/x/sys/unix"
 )
 
 type modeEnum int
@@ -26,13 +30,31 @@ const (
 	modeList      modeEnum = 0
 	modeSearch    modeEnum = 1
 	modeEnterPath modeEnum = 2
+	modeConfirm   modeEnum = 3
+	modeTrash     modeEnum = 4
+	modePrompt    modeEnum = 5
+	modeFind      modeEnum = 6
+	modeGrep      modeEnum = 7
+	modeBookmarks modeEnum = 8
+	modeJump      modeEnum = 9
+	modeHistory   modeEnum = 10
 )
 
+type clipboardOpEnum int
+
+const (
+	clipboardOpCopy clipboardOpEnum = 0
+	clipboardOpCut  clipboardOpEnum = 1
+)
+
+// unreadableMark follows the names of the items that cannot be read, on the list without details
+const unreadableMark = " !"
+
 type Model struct {
 	// state
 	path          string
 	previousPath  string
-	inputPath     string
+	inputPath     string // modeEnterPath input
 	cursorIx      int
 	rowOffset     int
 	colSize       int
@@ -46,7 +68,61 @@ type Model struct {
 	mode          modeEnum
 	username      string
 	showDetails   bool
+	showPreview   bool
 	searchInput   string
+	selection     map[string]int64 // Selected absolute paths and their sizes (sizeUnknown while calculating)
+	clipboard     []string         // Absolute paths marked to be copied or moved
+	clipboardOp   clipboardOpEnum
+	err           error        // Last error, displayed on the header
+	pick          *pickOptions // != nil on the file picker mode
+	shell         shellEnum    // Syntax of the emitted commands
+	cwdFile       string       // != "" to write the final directory to this file, instead of outputting a cd command
+	exitCode      int
+	returnMode    modeEnum // Mode to return to, after modeConfirm or modePrompt
+	archive       *archive // != nil when browsing an archive, which is on path
+	archiveDir    string   // Current folder inside the archive ("" for its root)
+	// Preview pane
+	previewKey   string // Path, first line and size of the loaded preview
+	previewLines []string
+	// modeFind and modeGrep
+	findGen     int
+	findCancel  context.CancelFunc
+	findRunning bool
+	grepRegex   bool
+	// modeEnterPath
+	inputCursor        int      // Rune index on inputPath
+	pathCompletions    []string // Inputs completed with each folder that matches, when many match
+	pathCompletionIx   int      // Completion chosen by pressing tab again (-1 for none)
+	pathCompletionTail string   // Input after the cursor, kept while completing
+	pathHistory        []string // Entered paths, the most recent last
+	pathHistoryIx      int      // Index of the input on pathHistory (len(pathHistory) for a new input)
+	pathHistoryDraft   string   // New input, kept while browsing the history
+	// modeConfirm
+	confirmQuestion string
+	confirmAction   func() error
+	// modePrompt
+	promptTitle  string
+	promptInput  string
+	promptAction func(input string) error
+	promptCmd    tea.Cmd // Command started by promptAction
+	// Waiting for a bookmark key
+	keyPromptTitle  string
+	keyPromptAction func(k string) error
+	// modeBookmarks
+	bookmarks []bookmark
+	// Navigation history
+	backHistory    []historyEntry // The most recent last
+	forwardHistory []historyEntry // The next one last
+	// Cursor memory
+	cursors map[string]dirCursor // Focused item and scroll of the visited directories, by path
+	// Frecency visits not saved yet
+	visits []frecencyEntry
+	// modeJump
+	jumpEntries []frecencyEntry        // The database, loaded when the view is opened
+	jumpInfos   map[string]os.FileInfo // Info of the directories that exist, nil until they are checked
+	jumpGen     int
+	// Compression or extraction running on background
+	task *archiveTask
 }
 
 type Item struct {
@@ -55,8 +131,13 @@ type Item struct {
 	linkTargetInfo os.FileInfo // != nil when file is a symbolic link
 	linkTargetPath string      // != "" when file is a symbolic link
 	linkIsBroken   bool
-	emphasisTextIx [2]int // Start and end indexes of emphasis text
+	fullPath       string // != "" when the item is not on the current directory (e.g. trash entries)
+	line           int    // != 0 for content search results
+	archivePath    string // != "" for archive entries: the path inside the archive
+	infoErr        error  // != nil when the file info (or symlink target) could not be read
+	emphasisTextIx []int  // Start and end indexes of emphasis texts, in pairs
 	isSelected     bool
+	isInClipboard  bool
 	details        ItemDetails
 }
 
@@ -65,15 +146,19 @@ type ItemDetails struct {
 }
 
 func (thiss *Model) Init() tea.Cmd {
-	path, err := os.Getwd()
-	if err != nil {
-		panic(err)
+	path := thiss.path
+	if path == "" {
+		wd, err := os.Getwd()
+		if err != nil { // The working directory may have been deleted
+			thiss.err = err
+			wd, _ = os.UserHomeDir()
+		}
+		path = wd
 	}
-	currentUser, err := user.Current()
-	if err != nil {
-		panic(err.Error())
+	username := os.Getenv("USER")
+	if currentUser, err := user.Current(); err == nil {
+		username = currentUser.Username
 	}
-	username := currentUser.Username
 
 	thiss.path = path
 	thiss.previousPath = path
@@ -81,14 +166,19 @@ func (thiss *Model) Init() tea.Cmd {
 	thiss.height = 10
 	thiss.username = username
 	thiss.showDetails = config.SHOW_DETAILS
-	thiss.Ls()
+	thiss.showPreview = config.SHOW_PREVIEW
+	thiss.initCursors()
+	if err := thiss.Ls(); err != nil {
+		thiss.err = err
+	}
+	thiss.restoreCursor()
 	return nil
 }
 
 // https://github.com/charmbracelet/bubbletea/blob/master/key.go
 var (
 	keyEsc           = key.NewBinding(key.WithKeys("esc"))
-	keyQuit          = key.NewBinding(key.WithKeys("alt+enter"))
+	keyQuit          = key.NewBinding(key.WithKeys("alt+enter", "alt+q"))
 	keyQuitWithoutCd = key.NewBinding(key.WithKeys("ctrl+c"))
 	keyUp            = key.NewBinding(key.WithKeys("up"))
 	keyDown          = key.NewBinding(key.WithKeys("down"))
@@ -96,24 +186,57 @@ var (
 	keyRight         = key.NewBinding(key.WithKeys("right"))
 	keyEnter         = key.NewBinding(key.WithKeys("enter"))
 	keyTab           = key.NewBinding(key.WithKeys("tab"))
+	keyOpen          = key.NewBinding(key.WithKeys("enter", "tab"))
 	keySpace         = key.NewBinding(key.WithKeys(" "))
+	keySelect        = key.NewBinding(key.WithKeys(" "))
 	keyBackspace     = key.NewBinding(key.WithKeys("backspace"))
 	keyParent        = key.NewBinding(key.WithKeys("alt+backspace"))
 	keyPageUp        = key.NewBinding(key.WithKeys("pgup"))
 	keyPageDown      = key.NewBinding(key.WithKeys("pgdown"))
 	keyHome          = key.NewBinding(key.WithKeys("home"))
 	keyEnd           = key.NewBinding(key.WithKeys("end"))
-	keyCopy          = key.NewBinding(key.WithKeys("C"))
+	keyCopy          = key.NewBinding(key.WithKeys("alt+c"))
 	keyCut           = key.NewBinding(key.WithKeys("alt+x"))
 	keyPaste         = key.NewBinding(key.WithKeys("alt+v"))
 	keyDetails       = key.NewBinding(key.WithKeys("alt+d"))
 	keyClear         = key.NewBinding(key.WithKeys("ctrl+u"))
-	keySlash         = key.NewBinding(key.WithKeys("/"))
-	keyTilde         = key.NewBinding(key.WithKeys("~"))
-	keyPrev          = key.NewBinding(key.WithKeys("-"))
+	keyRoot          = key.NewBinding(key.WithKeys("alt+/"))
+	keyTilde         = key.NewBinding(key.WithKeys("alt+h", "alt+~"))
+	keyPrev          = key.NewBinding(key.WithKeys("alt+-"))
+	keyTrash         = key.NewBinding(key.WithKeys("delete"))
+	keyDelete        = key.NewBinding(key.WithKeys("alt+delete"))
+	keyTrashView     = key.NewBinding(key.WithKeys("alt+t"))
+	keyConfirm       = key.NewBinding(key.WithKeys("y", "Y"))
+	keyRename        = key.NewBinding(key.WithKeys("f2"))
+	keyBulkRename    = key.NewBinding(key.WithKeys("alt+r"))
+	keyNewFolder     = key.NewBinding(key.WithKeys("alt+m"))
+	keyNewFile       = key.NewBinding(key.WithKeys("alt+n"))
+	keySelectAll     = key.NewBinding(key.WithKeys("alt+a"))
+	keyInvertSel     = key.NewBinding(key.WithKeys("alt+i"))
+	keyClearSel      = key.NewBinding(key.WithKeys("alt+u"))
+	keySearch        = key.NewBinding(key.WithKeys()) // Not needed by default: typing starts the search
+	keyFind          = key.NewBinding(key.WithKeys("alt+f"))
+	keyGrep          = key.NewBinding(key.WithKeys("alt+g"))
+	keyGrepRegex     = key.NewBinding(key.WithKeys("ctrl+r"))
+	keyPreview       = key.NewBinding(key.WithKeys("alt+p"))
+	keyExtract       = key.NewBinding(key.WithKeys("alt+e"))
+	keyCompress      = key.NewBinding(key.WithKeys("alt+z"))
+	keyMark          = key.NewBinding(key.WithKeys("alt+b"))
+	keyJump          = key.NewBinding(key.WithKeys("alt+'"))
+	keyBookmarksView = key.NewBinding(key.WithKeys("alt+B"))
+	keyFrecentJump   = key.NewBinding(key.WithKeys("alt+j"))
+	keyBack          = key.NewBinding(key.WithKeys("alt+left"))
+	keyForward       = key.NewBinding(key.WithKeys("alt+right"))
+	keyHistoryView   = key.NewBinding(key.WithKeys("alt+y"))
+	keyEnterPath     = key.NewBinding(key.WithKeys("ctrl+l"))
 )
 
 func (thiss *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	m, cmd := thiss.update(msg)
+	return m, tea.Batch(cmd, thiss.loadPreview())
+}
+
+func (thiss *Model) update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
 		thiss.width = msg.Width
@@ -121,24 +244,266 @@ func (thiss *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		thiss.calculateColsAndRows()
 		return thiss, nil
 	case tea.KeyMsg:
+		thiss.err = nil
 		return thiss.updateStateList(msg)
+	case bulkRenameMsg:
+		thiss.applyBulkRename(msg)
+		return thiss, nil
+	case execDoneMsg:
+		thiss.err = msg.err
+		thiss.refresh()
+		return thiss, nil
+	case findResultsMsg:
+		return thiss, thiss.addFindResults(msg)
+	case jumpCheckedMsg:
+		thiss.setJumpChecked(msg)
+		return thiss, nil
+	case previewMsg:
+		thiss.setPreview(msg)
+		return thiss, nil
+	case archiveTaskDoneMsg:
+		thiss.finishArchiveTask(msg)
+		return thiss, nil
+	case archiveTaskTickMsg:
+		if msg.task == thiss.task {
+			return thiss, archiveTaskTick(msg.task)
+		}
+		return thiss, nil
+	case dirSizeMsg:
+		if _, found := thiss.selection[msg.path]; found {
+			thiss.selection[msg.path] = msg.size
+		}
+		return thiss, nil
 	}
 	return thiss, nil
 }
 
 func (thiss *Model) updateStateList(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch {
-	case key.Matches(msg, keyQuit):
-		fmt.Fprintf(os.Stderr, "\n")
-		fmt.Println(`cd "` + thiss.path + `"`)
+	case thiss.mode == modeConfirm && !key.Matches(msg, keyQuitWithoutCd):
+		thiss.mode = thiss.returnMode
+		if key.Matches(msg, keyConfirm) {
+			thiss.err = thiss.confirmAction()
+			thiss.refresh()
+		}
+		return thiss, nil
+
+	case thiss.mode == modePrompt:
+		return thiss.updatePrompt(msg)
+
+	case thiss.mode == modeEnterPath:
+		thiss.updateEnterPath(msg)
+		return thiss, nil
+
+	case thiss.keyPromptAction != nil: // Only a letter or digit runs the action, other keys cancel it
+		action := thiss.keyPromptAction
+		thiss.keyPromptTitle, thiss.keyPromptAction = "", nil
+		if msg.Type == tea.KeyRunes && !msg.Alt && isBookmarkKey(string(msg.Runes)) {
+			thiss.err = action(string(msg.Runes))
+		}
+		return thiss, nil
+
+	case key.Matches(msg, keyEsc) && thiss.task != nil && thiss.mode == modeList: // Other views leave with Esc
+		thiss.task.cancel()
+		return thiss, nil
+
+	case thiss.pick != nil && (thiss.mode == modeList || thiss.mode == modeSearch) &&
+		(key.Matches(msg, keyQuit) || (key.Matches(msg, keyOpen) && thiss.isFocusedOnFile())):
+		return thiss, thiss.confirmPick()
+
+	case thiss.pick != nil && (key.Matches(msg, keyQuitWithoutCd) || (key.Matches(msg, keyEsc) && thiss.mode == modeList)):
+		thiss.exitCode = 1
 		return thiss, tea.Quit
 
+	case msg.Type == tea.KeySpace && thiss.mode == modeSearch: // Space is not a KeyRunes, but it is typed too
+		thiss.typeSearch([]rune(" "))
+		return thiss, nil
+
+	case key.Matches(msg, keySelect, keySelectAll, keyInvertSel) && thiss.pick != nil && !thiss.pick.multi:
+		return thiss, nil
+
+	case key.Matches(msg, keyQuit):
+		return thiss, thiss.quitWithCd(thiss.shell.cdCmd(thiss.path))
+
 	case key.Matches(msg, keyEsc) && thiss.mode == modeList:
 		return thiss, tea.Quit
 
 	case key.Matches(msg, keyQuitWithoutCd):
 		return thiss, tea.Quit
 
+	case key.Matches(msg, keyEsc) && thiss.mode == modeTrash:
+		thiss.err = thiss.Ls()
+		thiss.changeMode(modeList)
+		thiss.fixCursor()
+		return thiss, nil
+
+	case key.Matches(msg, keyOpen) && thiss.mode == modeTrash:
+		if len(thiss.items) == 0 {
+			return thiss, nil
+		}
+		_, err := restoreFromTrash(trashOfItem(thiss.CurrentItem()))
+		thiss.err = err
+		thiss.refresh()
+		return thiss, nil
+
+	case key.Matches(msg, keyDelete) && thiss.mode == modeTrash:
+		if len(thiss.items) == 0 {
+			return thiss, nil
+		}
+		trash, name := trashOfItem(thiss.CurrentItem())
+		thiss.askConfirmation("Permanently delete "+thiss.CurrentItem().name+"?", func() error {
+			return purgeFromTrash(trash, name)
+		})
+		return thiss, nil
+
+	case key.Matches(msg, keyEsc) && thiss.mode == modeBookmarks:
+		thiss.err = thiss.Ls()
+		thiss.changeMode(modeList)
+		thiss.fixCursor()
+		return thiss, nil
+
+	case key.Matches(msg, keyOpen) && thiss.mode == modeBookmarks:
+		if b, ok := thiss.focusedBookmark(); ok {
+			thiss.jumpToBookmark(b.Key)
+		}
+		return thiss, nil
+
+	case key.Matches(msg, keyRename) && thiss.mode == modeBookmarks:
+		if b, ok := thiss.focusedBookmark(); ok {
+			thiss.askInput("Rename bookmark "+b.Key+" to", b.Name, func(input string) error {
+				err := renameBookmark(b.Key, input)
+				thiss.refresh()
+				return err
+			})
+		}
+		return thiss, nil
+
+	case key.Matches(msg, keyTrash, keyDelete) && thiss.mode == modeBookmarks:
+		if b, ok := thiss.focusedBookmark(); ok {
+			thiss.err = deleteBookmark(b.Key)
+			thiss.refresh()
+		}
+		return thiss, nil
+
+	case msg.Type == tea.KeyRunes && !msg.Alt && thiss.mode == modeBookmarks &&
+		!key.Matches(msg, keyUp, keyDown, keyLeft, keyRight, keyHome, keyEnd): // Typing a key jumps to its bookmark
+		if isBookmarkKey(string(msg.Runes)) {
+			thiss.jumpToBookmark(string(msg.Runes))
+		}
+		return thiss, nil
+
+	case key.Matches(msg, keyEsc) && thiss.mode == modeHistory:
+		thiss.err = thiss.Ls()
+		thiss.changeMode(modeList)
+		thiss.fixCursor()
+		return thiss, nil
+
+	case key.Matches(msg, keyOpen) && thiss.mode == modeHistory:
+		if len(thiss.items) > 0 {
+			thiss.goBack(thiss.cursorIx + 1)
+		}
+		return thiss, nil
+
+	case key.Matches(msg, keyEsc) && thiss.mode == modeJump:
+		thiss.changeMode(modeList)
+		return thiss, nil
+
+	case key.Matches(msg, keyOpen) && thiss.mode == modeJump:
+		thiss.openJumpResult()
+		return thiss, nil
+
+	case msg.Type == tea.KeyRunes && !msg.Alt && thiss.mode == modeJump:
+		thiss.typeJump(msg.Runes)
+		return thiss, nil
+
+	case key.Matches(msg, keySpace) && thiss.mode == modeJump: // Separates the terms
+		thiss.typeJump([]rune(" "))
+		return thiss, nil
+
+	case key.Matches(msg, keyBackspace) && thiss.mode == modeJump:
+		runes := []rune(thiss.searchInput)
+		if len(runes) > 0 {
+			thiss.searchInput = string(runes[:len(runes)-1])
+		}
+		thiss.typeJump(nil)
+		return thiss, nil
+
+	case key.Matches(msg, keyEsc) && thiss.mode == modeFind:
+		thiss.changeMode(modeList)
+		return thiss, nil
+
+	case key.Matches(msg, keyOpen) && thiss.mode == modeFind:
+		thiss.openFindResult()
+		return thiss, nil
+
+	case msg.Type == tea.KeyRunes && !msg.Alt && thiss.mode == modeFind:
+		return thiss, thiss.typeFind(msg.Runes)
+
+	case key.Matches(msg, keyBackspace) && thiss.mode == modeFind:
+		runes := []rune(thiss.searchInput)
+		if len(runes) > 0 {
+			thiss.searchInput = string(runes[:len(runes)-1])
+		}
+		return thiss, thiss.startFind()
+
+	case key.Matches(msg, keyEsc) && thiss.mode == modeGrep:
+		thiss.changeMode(modeList)
+		return thiss, nil
+
+	case key.Matches(msg, keyOpen) && thiss.mode == modeGrep:
+		return thiss, thiss.openGrepResult()
+
+	case key.Matches(msg, keyGrepRegex) && thiss.mode == modeGrep:
+		thiss.grepRegex = !thiss.grepRegex
+		return thiss, thiss.startGrep()
+
+	case msg.Type == tea.KeyRunes && !msg.Alt && thiss.mode == modeGrep:
+		return thiss, thiss.typeGrep(msg.Runes)
+
+	case key.Matches(msg, keyBackspace) && thiss.mode == modeGrep:
+		runes := []rune(thiss.searchInput)
+		if len(runes) > 0 {
+			thiss.searchInput = string(runes[:len(runes)-1])
+		}
+		return thiss, thiss.startGrep()
+
+	case thiss.archive != nil && (thiss.mode == modeList || thiss.mode == modeSearch) &&
+		key.Matches(msg, keyFind, keyGrep, keyPaste, keyBulkRename, keyNewFolder, keyNewFile, keyCompress, keyMark):
+		thiss.err = errInsideArchive
+		return thiss, nil
+
+	case key.Matches(msg, keyExtract) && (thiss.mode == modeList || thiss.mode == modeSearch):
+		thiss.extractFocused()
+		return thiss, nil
+
+	case key.Matches(msg, keyCompress) && (thiss.mode == modeList || thiss.mode == modeSearch):
+		thiss.compressTargets()
+		return thiss, nil
+
+	case key.Matches(msg, keyGrep) && (thiss.mode == modeList || thiss.mode == modeSearch):
+		thiss.changeMode(modeGrep)
+		return thiss, thiss.startGrep()
+
+	case key.Matches(msg, keyFind) && (thiss.mode == modeList || thiss.mode == modeSearch):
+		thiss.changeMode(modeFind)
+		return thiss, thiss.startFind()
+
+	case key.Matches(msg, keyFrecentJump) && (thiss.mode == modeList || thiss.mode == modeSearch):
+		cmd := thiss.startJump()
+		thiss.changeMode(modeJump)
+		return thiss, cmd
+
+	case msg.Type == tea.KeyRunes && !msg.Alt && thiss.mode == modeSearch: // Typing on search has priority over actions
+		thiss.typeSearch(msg.Runes)
+		return thiss, nil
+
+	case key.Matches(msg, keySearch) && thiss.mode == modeList:
+		thiss.searchFilter("")
+		thiss.cursorIx = 0
+		thiss.rowOffset = 0
+		thiss.changeMode(modeSearch)
+		return thiss, nil
+
 	case key.Matches(msg, keyLeft):
 		thiss.cursorAdd(-1)
 		if !thiss.isCursorDisplayed() {
@@ -187,113 +552,161 @@ func (thiss *Model) updateStateList(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		thiss.rowOffset = thiss.cursorRowIx()
 		return thiss, nil
 
-	case key.Matches(msg, keyEnter, keyTab) && (thiss.mode == modeList || thiss.mode == modeSearch):
+	case key.Matches(msg, keyOpen) && (thiss.mode == modeList || thiss.mode == modeSearch) && thiss.isFocusedOnArchive():
+		thiss.openFocusedArchive()
+		return thiss, nil
+
+	case key.Matches(msg, keyOpen) && (thiss.mode == modeList || thiss.mode == modeSearch) &&
+		thiss.cwdFile != "" && thiss.isFocusedOnFile():
+		return thiss, thiss.runFocusedFile()
+
+	case key.Matches(msg, keyOpen) && (thiss.mode == modeList || thiss.mode == modeSearch):
 		shouldExit, hasFailed, exitCmd := thiss.cursorEnter()
 		if hasFailed {
 			return thiss, nil
 		}
 		thiss.changeMode(modeList)
 		if shouldExit {
-			fmt.Fprintf(os.Stderr, "\n")
-			fmt.Println(exitCmd)
-			return thiss, tea.Quit
+			return thiss, thiss.quitWithCd(exitCmd)
 		}
 		return thiss, nil
 
-	// case key.Matches(msg, keySlash) && thiss.mode == modeSearch: // Slash enters directory, on searchMode
-	// 	thiss.cursorEnter()
-	// 	thiss.changeMode(modeList)
-	// 	return thiss, nil
+	case key.Matches(msg, keyMark) && (thiss.mode == modeList || thiss.mode == modeSearch):
+		thiss.markCurrentDir()
+		return thiss, nil
+
+	case key.Matches(msg, keyJump) && (thiss.mode == modeList || thiss.mode == modeSearch):
+		thiss.askKey("Jump to bookmark", thiss.jumpToBookmark)
+		return thiss, nil
+
+	case key.Matches(msg, keyBookmarksView) && (thiss.mode == modeList || thiss.mode == modeSearch):
+		thiss.changeMode(modeBookmarks)
+		return thiss, nil
 
 	case key.Matches(msg, keyTilde) && thiss.mode == modeList:
 		homePath, _ := os.UserHomeDir()
-		thiss.previousPath = thiss.path
-		thiss.path = homePath
-		thiss.Ls()
-		thiss.calculateColsAndRows()
+		thiss.goToPath(homePath)
 		return thiss, nil
 
 	case key.Matches(msg, keyPrev) && thiss.mode == modeList: // Go to previous path
 		var swap = thiss.path
-		thiss.path = thiss.previousPath
-		thiss.previousPath = swap
-		thiss.Ls()
-		thiss.calculateColsAndRows()
-		thiss.setOffsetToMiddleScreen()
+		if thiss.changeDir(thiss.previousPath) {
+			thiss.previousPath = swap
+			thiss.calculateColsAndRows()
+			thiss.setOffsetToMiddleScreen()
+		}
 		return thiss, nil
 
 	case key.Matches(msg, keyParent) && thiss.mode == modeList:
 		thiss.goParent()
 		return thiss, nil
 
-	case key.Matches(msg, keySpace) && (thiss.mode == modeList || thiss.mode == modeSearch):
-		// TODO: Selections
-		// thiss.toggleSelection()
+	case key.Matches(msg, keyBack) && thiss.mode == modeList:
+		thiss.goBack(1)
 		return thiss, nil
 
-	case key.Matches(msg, keyCopy):
-		thiss.toggleSelection()
+	case key.Matches(msg, keyForward) && thiss.mode == modeList:
+		thiss.goForward()
 		return thiss, nil
 
-	case key.Matches(msg, keyCut):
-		thiss.toggleSelection()
+	case key.Matches(msg, keyHistoryView) && thiss.mode == modeList:
+		thiss.changeMode(modeHistory)
 		return thiss, nil
 
-	case key.Matches(msg, keyPaste) && thiss.mode == modeList:
-		thiss.toggleSelection()
-		return thiss, nil
+	case key.Matches(msg, keySelect) && (thiss.mode == modeList || thiss.mode == modeSearch):
+		cmd := thiss.toggleSelection()
+		thiss.cursorAdd(1)
+		if !thiss.isCursorDisplayed() {
+			thiss.addRowOffset(1)
+		}
+		return thiss, cmd
 
-	case key.Matches(msg, keyDetails) && thiss.mode == modeList:
-		thiss.toggleDetails()
+	case key.Matches(msg, keySelectAll) && (thiss.mode == modeList || thiss.mode == modeSearch):
+		return thiss, thiss.selectAll()
+
+	case key.Matches(msg, keyInvertSel) && (thiss.mode == modeList || thiss.mode == modeSearch):
+		return thiss, thiss.invertSelection()
+
+	case key.Matches(msg, keyClearSel) && (thiss.mode == modeList || thiss.mode == modeSearch):
+		thiss.clearSelection()
 		return thiss, nil
 
-		// Disable modeEnterPath for now
-	// case key.Matches(msg, keySlash) && thiss.mode == modeList: // Change to _modeEnterPath
-	// 	thiss.changeMode(modeEnterPath)
-	// 	return thiss, nil
+	case key.Matches(msg, keyCopy) && (thiss.mode == modeList || thiss.mode == modeSearch):
+		thiss.toggleClipboard(clipboardOpCopy)
+		return thiss, nil
 
-	case key.Matches(msg, keySlash) && thiss.mode == modeList: // Go to root
-		thiss.goToPath("/")
+	case key.Matches(msg, keyCut) && (thiss.mode == modeList || thiss.mode == modeSearch):
+		thiss.toggleClipboard(clipboardOpCut)
 		return thiss, nil
 
-	case msg.Type == tea.KeyRunes && thiss.mode == modeEnterPath:
-		thiss.inputPath += string(msg.Runes)
+	case key.Matches(msg, keyPaste) && thiss.mode == modeList:
+		thiss.paste()
 		return thiss, nil
 
-	case key.Matches(msg, keySpace) && thiss.mode == modeEnterPath: // (a Bug? cannot detect space)
-		thiss.inputPath += string(" ")
+	case key.Matches(msg, keyTrash) && (thiss.mode == modeList || thiss.mode == modeSearch):
+		thiss.trashTargets()
 		return thiss, nil
 
-	case key.Matches(msg, keyBackspace) && thiss.mode == modeEnterPath:
-		thiss.inputPath = thiss.inputPath[:len(thiss.inputPath)-1]
-		if thiss.inputPath == "" {
-			thiss.changeMode(modeList)
+	case key.Matches(msg, keyDelete) && (thiss.mode == modeList || thiss.mode == modeSearch):
+		targets := thiss.targetPaths()
+		if len(targets) == 0 {
+			return thiss, nil
 		}
+		thiss.askConfirmation(fmt.Sprintf("Permanently delete %d item(s)?", len(targets)), func() error {
+			return deletePaths(targets)
+		})
 		return thiss, nil
 
-	case key.Matches(msg, keyEnter) && thiss.mode == modeEnterPath:
-		if thiss.isPathOk(thiss.inputPath) {
-			thiss.previousPath = thiss.path
-			thiss.path = filepath.Clean(thiss.inputPath)
-			thiss.Ls()
-			thiss.rowOffset = 0
-			thiss.cursorIx = 0
-			thiss.changeMode(modeList)
+	case key.Matches(msg, keyRename) && (thiss.mode == modeList || thiss.mode == modeSearch):
+		if len(thiss.items) == 0 || !isSelectable(thiss.CurrentItem()) {
+			return thiss, nil
 		}
+		oldName := thiss.CurrentItem().fileInfo.Name()
+		thiss.askInput("Rename to", oldName, func(input string) error {
+			return thiss.renameItem(oldName, input)
+		})
 		return thiss, nil
 
-	case msg.Type == tea.KeyRunes: // Change to _modeSearch
-		if unicode.IsLetter(msg.Runes[0]) && unicode.IsLower(msg.Runes[0]) {
-			thiss.searchInput += string(msg.Runes)
-			thiss.searchFilter(thiss.searchInput)
-			thiss.cursorIx = 0
-			thiss.rowOffset = 0
-			thiss.changeMode(modeSearch)
-		}
+	case key.Matches(msg, keyBulkRename) && (thiss.mode == modeList || thiss.mode == modeSearch):
+		return thiss, thiss.bulkRename()
+
+	case key.Matches(msg, keyNewFolder) && thiss.mode == modeList:
+		thiss.askInput("New folder", "", thiss.createFolder)
+		return thiss, nil
+
+	case key.Matches(msg, keyNewFile) && thiss.mode == modeList:
+		thiss.askInput("New file", "", thiss.createFile)
+		return thiss, nil
+
+	case key.Matches(msg, keyTrashView) && thiss.mode == modeList:
+		thiss.changeMode(modeTrash)
+		return thiss, nil
+
+	case key.Matches(msg, keyDetails) && thiss.mode == modeList:
+		thiss.toggleDetails()
+		return thiss, nil
+
+	case key.Matches(msg, keyPreview) && thiss.mode != modeEnterPath:
+		thiss.togglePreview()
+		return thiss, nil
+
+	case key.Matches(msg, keyEnterPath) && (thiss.mode == modeList || thiss.mode == modeSearch):
+		thiss.changeMode(modeEnterPath)
+		return thiss, nil
+
+	case key.Matches(msg, keyRoot) && thiss.mode == modeList: // Go to root
+		thiss.goToPath("/")
+		return thiss, nil
+
+	case msg.Type == tea.KeyRunes && !msg.Alt && thiss.mode == modeList: // Change to _modeSearch
+		thiss.typeSearch(msg.Runes)
 		return thiss, nil
 
 	case key.Matches(msg, keyBackspace) && thiss.mode == modeSearch:
-		thiss.searchInput = thiss.searchInput[:len(thiss.searchInput)-1]
+		runes := []rune(thiss.searchInput)
+		if len(runes) > 0 {
+			thiss.searchInput = string(runes[:len(runes)-1])
+		}
 		thiss.cursorIx = 0
 		thiss.rowOffset = 0
 		if thiss.searchInput == "" {
@@ -314,12 +727,12 @@ func (thiss *Model) updateStateList(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 }
 
 func (thiss *Model) View() string {
-	if thiss.mode == modeList || thiss.mode == modeSearch {
-		return thiss.renderList()
-	} else if thiss.mode == modeEnterPath {
-		return thiss.renderListScreen(thiss.renderHeader(), "", "")
+	if thiss.mode == modeEnterPath {
+		completions := lipgloss.NewStyle().Width(thiss.width).Render(thiss.renderPathCompletions())
+		footer := term.Gray(joinHints("[tab] Complete", "[up/down] History", "[enter] Go", "[esc] Back"), false)
+		return thiss.renderListScreen(thiss.renderHeader(), completions, footer)
 	}
-	return ""
+	return thiss.renderList()
 }
 
 func (thiss *Model) renderList() string {
@@ -351,7 +764,12 @@ func (thiss *Model) renderList() string {
 		}
 	}
 
-	return thiss.renderListScreen(thiss.renderHeader(), listOut, renderFooter())
+	if thiss.isPreviewShown() {
+		listOut = lipgloss.NewStyle().MaxWidth(thiss.listWidth()).Render(listOut)
+		listOut = lipgloss.NewStyle().Width(thiss.listWidth()).Render(listOut)
+		listOut = lipgloss.JoinHorizontal(lipgloss.Top, listOut, thiss.renderPreview(thiss.rowsDisplayed()))
+	}
+	return thiss.renderListScreen(thiss.renderHeader(), listOut, thiss.renderFooter())
 }
 
 func (thiss *Model) renderListScreen(header, list, footer string) string {
@@ -362,45 +780,122 @@ func (thiss *Model) renderListScreen(header, list, footer string) string {
 
 func (thiss *Model) renderHeader() string {
 	o := thiss.username + ": "
+	if thiss.pick != nil {
+		o = term.Violet("pick", false) + " " + o
+	}
 	if thiss.mode == modeList {
-		o += thiss.path
+		o += thiss.displayPath()
 	} else if thiss.mode == modeEnterPath {
-		if thiss.isPathOk(thiss.inputPath) {
-			o += term.Green(thiss.inputPath, false) +
-				"\n" +
-				term.Gray("Manual path input mode. Please type the desired path.", false) +
+		path := expandPathInput(thiss.inputPath, thiss.path)
+		if thiss.isPathOk(path) {
+			o += thiss.renderPathInput(term.Green) +
 				"\n" +
-				term.Gray("Press <enter> to enter path", false)
+				term.Gray(path, false)
 		} else {
-			o += term.Red(thiss.inputPath, false) +
+			o += thiss.renderPathInput(term.Red) +
 				"\n" +
-				term.Gray("Invalid path", false) +
-				"\n" +
-				term.Gray("Fix it or press <esc> to exit path input mode", false)
+				term.Gray("Not a folder: "+path, false)
 		}
 	} else if thiss.mode == modeSearch {
-		o = strings.TrimSuffix(o+thiss.path, "/") + "/" + term.Violet(thiss.searchInput, false)
+		o = strings.TrimSuffix(o+thiss.displayPath(), "/") + "/" + term.Violet(thiss.searchInput, false)
+	} else if thiss.mode == modeFind {
+		status := fmt.Sprintf(" (%d found)", len(thiss.items))
+		if thiss.findRunning {
+			status = fmt.Sprintf(" (%d found, searching...)", len(thiss.items))
+		}
+		o = strings.TrimSuffix(o+thiss.path, "/") + "/**/" + term.Violet(thiss.searchInput, false) + term.Gray(status, false)
+	} else if thiss.mode == modeGrep {
+		status := fmt.Sprintf(" (%d lines)", len(thiss.items))
+		if thiss.findRunning {
+			status = fmt.Sprintf(" (%d lines, searching...)", len(thiss.items))
+		}
+		kind := "grep"
+		if thiss.grepRegex {
+			kind = "grep regexp"
+		}
+		o += thiss.path + " " + kind + ": " + term.Violet(thiss.searchInput, false) + term.Gray(status, false)
+	} else if thiss.mode == modeTrash {
+		o += trashDir() + term.Gray(fmt.Sprintf(" (%d items)", len(thiss.items)), false)
+	} else if thiss.mode == modeHistory {
+		o += "history" + term.Gray(fmt.Sprintf(" (%d dirs back, %d forward)", len(thiss.backHistory),
+			len(thiss.forwardHistory)), false)
+	} else if thiss.mode == modeJump {
+		o += "jump: " + term.Violet(thiss.searchInput, false) + term.Gray(fmt.Sprintf(" (%d dirs)", len(thiss.items)), false)
+	} else if thiss.mode == modeBookmarks {
+		o += bookmarksPath() + term.Gray(fmt.Sprintf(" (%d bookmarks)", len(thiss.items)), false)
+	} else if thiss.mode == modeConfirm {
+		o += term.Yellow(thiss.confirmQuestion, false) + term.Gray(" [y/N]", false)
+	} else if thiss.mode == modePrompt {
+		o += thiss.displayPath() + "\n" + thiss.promptTitle + ": " + term.Green(thiss.promptInput, false) + term.Violet("_", false)
+	}
+	if thiss.keyPromptAction != nil {
+		o += "\n" + term.Yellow(thiss.keyPromptTitle, false) + term.Gray(" [any other key cancels]", false)
+	}
+	if thiss.err != nil {
+		o += "\n" + term.Red(thiss.err.Error(), false)
 	}
 	return o
 }
 
-func renderFooter() string {
-	s := "" +
-		// "space: Select   shift+c: Copy   alt+x: Cut      alt+v: Paste   del: Delete" +
-		// "\n" +
-		// "alt+h: Help     esc: Quit       lower: Search   alt+d: Details"
-		// "[/] Go to root   [~] Go to home   [alt+backspace] Go to parent" +
-		// "\n" +
-		"[a-z] Search   [alt+d] Details   [alt+enter] Quit   [ctrl+c] Quit without cd"
+func (thiss *Model) renderFooter() string {
+	search := "[type] Search"
+	if len(keySearch.Keys()) > 0 {
+		search = keyHint(keySearch, "Search")
+	}
+	s := joinHints(search, keyHint(keyDetails, "Details"), keyHint(keyPreview, "Preview"), keyHint(keyQuit, "Quit"),
+		keyHint(keyQuitWithoutCd, "Quit without cd"))
+
+	if thiss.mode == modeFind || thiss.mode == modeJump || thiss.mode == modeHistory {
+		s = joinHints(keyHint(keyOpen, "Go to"), "[esc] Back")
+	} else if thiss.mode == modeGrep {
+		regex := "Regexp"
+		if thiss.grepRegex {
+			regex = "Literal"
+		}
+		s = joinHints(keyHint(keyOpen, "Open"), keyHint(keyGrepRegex, regex), "[esc] Back")
+	} else if thiss.archive != nil && (thiss.mode == modeList || thiss.mode == modeSearch) {
+		s = joinHints(search, keyHint(keyExtract, "Extract"), keyHint(keyParent, "Back"), keyHint(keyQuit, "Quit"))
+	} else if thiss.mode == modeTrash {
+		s = joinHints(keyHint(keyOpen, "Restore"), keyHint(keyDelete, "Delete permanently"), "[esc] Back")
+	} else if thiss.mode == modeBookmarks {
+		s = joinHints(keyHint(keyOpen, "Go to"), "[key] Jump", keyHint(keyRename, "Rename"), keyHint(keyTrash, "Delete"),
+			"[esc] Back")
+	} else if thiss.pick != nil {
+		s = joinHints(search, keyHint(keyQuit, "Pick"), keyHint(keyQuitWithoutCd, "Cancel"))
+		if thiss.pick.multi {
+			s = joinHints(keyHint(keySelect, "Select"), s)
+		}
+	}
 
-	return term.Gray(s, false)
+	if len(thiss.clipboard) > 0 {
+		op := "copy"
+		if thiss.clipboardOp == clipboardOpCut {
+			op = "move"
+		}
+		s = joinHints(keyHint(keyPaste, fmt.Sprintf("Paste (%s %d items)", op, len(thiss.clipboard))), s)
+	}
+	if thiss.task != nil {
+		s = joinHints("[esc] Cancel", s)
+	}
+	s = term.Gray(s, false)
+	if len(thiss.selection) > 0 {
+		s = term.Orange(thiss.selectionSummary(), false) + "   " + s
+	}
+	if thiss.task != nil {
+		s = term.Orange(thiss.task.progress(), false) + "   " + s
+	}
+	return s
 }
 
-func (thiss *Model) Ls() {
+// Ls lists the current directory. On error, the items are kept unchanged
+func (thiss *Model) Ls() error {
+	if thiss.archive != nil {
+		return thiss.lsArchive()
+	}
 	resolvedPath, _ := filepath.EvalSymlinks(thiss.path)
 	files, err := os.ReadDir(resolvedPath)
 	if err != nil {
-		panic(err)
+		return err
 	}
 	addRelDirs := func() []Item {
 		ret := []Item{}
@@ -415,10 +910,7 @@ func (thiss *Model) Ls() {
 			return ret
 		}
 		if config.ADD_TWO_DOT_FOLDER {
-			previousDirStat, err := os.Stat(filepath.Clean(filepath.Join(thiss.path, "..")))
-			if err != nil {
-				panic(err)
-			}
+			previousDirStat, _ := os.Stat(filepath.Clean(filepath.Join(thiss.path, "..")))
 			var perm, username, group, size, date string = getDetails(previousDirStat)
 			ret = append(ret, Item{
 				name:     "../",
@@ -436,11 +928,13 @@ func (thiss *Model) Ls() {
 		return ret
 	}
 
-	thiss.items = addRelDirs()
+	items := addRelDirs()
 	for _, f := range files {
+		var infoErr error
 		info, err := f.Info()
-		if err != nil {
-			panic(err)
+		if err != nil { // Deleted after ReadDir, or unreadable
+			infoErr = err
+			info = unreadableFileInfo{f}
 		}
 		name := info.Name()
 		if info.IsDir() {
@@ -449,29 +943,25 @@ func (thiss *Model) Ls() {
 		linkTarget := ""
 		linkIsBroken := false
 		var linkTargetInfo os.FileInfo
-		if isFileSymlink(info) {
-			target, err := os.Readlink(filepath.Join(resolvedPath, name))
-			if err != nil {
-				panic(err)
-			}
-			linkTarget = target
-			targetResolvedFullPath, err := filepath.EvalSymlinks(filepath.Join(resolvedPath, name))
+		if infoErr == nil && isFileSymlink(info) {
+			linkTarget, infoErr = os.Readlink(filepath.Join(resolvedPath, name))
+			targetInfo, err := os.Stat(filepath.Join(resolvedPath, name))
 			if err != nil {
 				linkIsBroken = true
+				targetInfo = info
 			}
-			isRelativeTarget := !filepath.IsAbs(targetResolvedFullPath)
-			if isRelativeTarget {
-				targetResolvedFullPath = filepath.Clean(filepath.Join(resolvedPath, targetResolvedFullPath))
-			}
-			linkTargetInfo, err = os.Lstat(targetResolvedFullPath)
-			if err != nil {
-				panic(err)
+			linkTargetInfo = targetInfo
+		}
+		if infoErr == nil && !linkIsBroken { // Listed, but not readable, like folders with mode 000
+			if err := unix.Access(filepath.Join(resolvedPath, info.Name()), unix.R_OK); err != nil {
+				infoErr = &os.PathError{Op: "access", Path: filepath.Join(resolvedPath, info.Name()), Err: err}
 			}
 		}
 		var perm, username, group, size, date string = getDetails(info)
-		thiss.items = append(thiss.items, Item{
+		items = append(items, Item{
 			name:     name,
 			fileInfo: info,
+			infoErr:  infoErr,
 			details: ItemDetails{
 				Perm:     perm,
 				Username: username,
@@ -486,11 +976,53 @@ func (thiss *Model) Ls() {
 	}
 
 	if config.LIST_FOLDERS_FIRST {
-		thiss.items = sortItemsFoldersFirst(thiss.items)
+		items = sortItemsFoldersFirst(items)
+	}
+	thiss.items = items
+	thiss.dirItems = items
+	thiss.refreshMarks()
+	return nil
+}
+
+// changeDir lists path and makes it the current directory, leaving the archive if browsing one. On
+// failure, the current directory is kept and the error is displayed
+func (thiss *Model) changeDir(path string) bool {
+	from, fromArchive := thiss.currentHistoryEntry(), thiss.archive != nil
+	if !thiss.loadDir(path) {
+		return false
+	}
+	if !fromArchive { // Archive folders are not on the history
+		thiss.pushHistory(from)
+	}
+	return true
+}
+
+// loadDir lists path, making it the current directory. On error, the current directory is kept
+func (thiss *Model) loadDir(path string) bool {
+	thiss.rememberCursor()
+	oldPath, oldArchive := thiss.path, thiss.archive
+	thiss.path = path
+	thiss.archive = nil
+	if err := thiss.Ls(); err != nil {
+		thiss.path, thiss.archive = oldPath, oldArchive
+		thiss.err = err
+		return false
 	}
-	thiss.dirItems = thiss.items
+	return true
+}
+
+// unreadableFileInfo is used for entries whose info cannot be read. Only the name and type are known
+type unreadableFileInfo struct {
+	entry os.DirEntry
 }
 
+func (u unreadableFileInfo) Name() string       { return u.entry.Name() }
+func (u unreadableFileInfo) Size() int64        { return 0 }
+func (u unreadableFileInfo) Mode() os.FileMode  { return u.entry.Type() }
+func (u unreadableFileInfo) ModTime() time.Time { return time.Time{} }
+func (u unreadableFileInfo) IsDir() bool        { return u.entry.IsDir() }
+func (u unreadableFileInfo) Sys() interface{}   { return nil }
+
 func sortItemsFoldersFirst(items []Item) []Item {
 	folders := []Item{}
 	files := []Item{}
@@ -539,8 +1071,12 @@ func addColorByFileType(text string, item Item, isFocused bool, marks []int) str
 		text = term.Violet(text, true)
 	} else if item.isSelected {
 		text = term.Orange(text, true)
+	} else if item.isInClipboard {
+		text = term.Yellow(text, true)
 	} else if item.fileInfo == nil {
 		text = addTextEmphasisAndBlue(text, marks)
+	} else if item.infoErr != nil {
+		text = addTextEmphasisAndRed(text, marks)
 	} else if isSymlinkBroken {
 		text = addTextEmphasisAndRedBg(text, marks)
 	} else if isSymlink {
@@ -558,45 +1094,42 @@ func addColorByFileType(text string, item Item, isFocused bool, marks []int) str
 }
 
 func addTextEmphasisAndBlue(text string, marks []int) string {
-	s1 := text[0:marks[0]]
-	s2 := text[marks[0]:marks[1]]
-	s3 := text[marks[1]:]
-	return term.Blue(s1, false) + term.Emphasis(s2) + term.Blue(s3, false)
+	return addTextEmphasis(text, marks, func(s string) string { return term.Blue(s, false) })
+}
+
+// addTextEmphasis emphasizes the marked texts (start and end indexes, in pairs) and colors the rest
+func addTextEmphasis(text string, marks []int, color func(string) string) string {
+	out := ""
+	last := 0
+	for ix := 0; ix+1 < len(marks); ix += 2 {
+		out += color(text[last:marks[ix]]) + term.Emphasis(text[marks[ix]:marks[ix+1]])
+		last = marks[ix+1]
+	}
+	return out + color(text[last:])
 }
 
 func addTextEmphasisAndNothing(text string, marks []int) string {
-	s1 := text[0:marks[0]]
-	s2 := text[marks[0]:marks[1]]
-	s3 := text[marks[1]:]
-	return s1 + term.Emphasis(s2) + s3
+	return addTextEmphasis(text, marks, func(s string) string { return s })
 }
 
 func addTextEmphasisAndGreen(text string, marks []int) string {
-	s1 := text[0:marks[0]]
-	s2 := text[marks[0]:marks[1]]
-	s3 := text[marks[1]:]
-	return term.Green(s1, false) + term.Emphasis(s2) + term.Green(s3, false)
+	return addTextEmphasis(text, marks, func(s string) string { return term.Green(s, false) })
 }
 
 func addTextEmphasisAndYellow(text string, marks []int) string {
-	s1 := text[0:marks[0]]
-	s2 := text[marks[0]:marks[1]]
-	s3 := text[marks[1]:]
-	return term.Yellow(s1, false) + term.Emphasis(s2) + term.Yellow(s3, false)
+	return addTextEmphasis(text, marks, func(s string) string { return term.Yellow(s, false) })
 }
 
 func addTextEmphasisAndCyan(text string, marks []int) string {
-	s1 := text[0:marks[0]]
-	s2 := text[marks[0]:marks[1]]
-	s3 := text[marks[1]:]
-	return term.Cyan(s1, false) + term.Emphasis(s2) + term.Cyan(s3, false)
+	return addTextEmphasis(text, marks, func(s string) string { return term.Cyan(s, false) })
+}
+
+func addTextEmphasisAndRed(text string, marks []int) string {
+	return addTextEmphasis(text, marks, func(s string) string { return term.Red(s, false) })
 }
 
 func addTextEmphasisAndRedBg(text string, marks []int) string {
-	s1 := text[0:marks[0]]
-	s2 := text[marks[0]:marks[1]]
-	s3 := text[marks[1]:]
-	return term.Red(s1, true) + term.Emphasis(s2) + term.Red(s3, true)
+	return addTextEmphasis(text, marks, func(s string) string { return term.Red(s, true) })
 }
 
 func getDetails(fileInfo os.FileInfo) (perm, username, group, size, date string) {
@@ -606,8 +1139,13 @@ func getDetails(fileInfo os.FileInfo) (perm, username, group, size, date string)
 	// perm
 	perm = fileInfo.Mode().String()
 	// User
-	var fsys = fileInfo.Sys()
-	var uid = fsys.(*syscall.Stat_t).Uid
+	var fsys, ok = fileInfo.Sys().(*syscall.Stat_t)
+	if !ok { // Unreadable file info
+		perm = strings.Repeat("?", len(perm))
+		username, group, size, date = "?", "?", "?", "?"
+		return
+	}
+	var uid = fsys.Uid
 	var us, err = user.LookupId(strconv.Itoa(int(uid)))
 	if err != nil {
 		username = "( " + strconv.Itoa(int(uid)) + " )"
@@ -615,7 +1153,7 @@ func getDetails(fileInfo os.FileInfo) (perm, username, group, size, date string)
 		username = us.Username
 	}
 	// Group
-	var gid = fsys.(*syscall.Stat_t).Gid
+	var gid = fsys.Gid
 	gr, err := user.LookupGroupId(strconv.Itoa(int(gid)))
 	if err != nil {
 		group = "( " + strconv.Itoa(int(gid)) + " )"
@@ -629,6 +1167,23 @@ func getDetails(fileInfo os.FileInfo) (perm, username, group, size, date string)
 	return
 }
 
+// pathItem returns the list item of a file that is not on the current directory. err is the error of
+// reading info, which leaves the details blank
+func pathItem(name, path string, info os.FileInfo, err error) Item {
+	item := Item{name: name, fileInfo: info, fullPath: path, infoErr: err}
+	if err == nil {
+		var perm, username, group, size, date string = getDetails(info)
+		item.details = ItemDetails{
+			Perm:     perm,
+			Username: username,
+			Group:    group,
+			Size:     size,
+			Date:     date,
+		}
+	}
+	return item
+}
+
 func getDetailsSizes(all []Item) (permSz, userSz, groupSz, sizeSz, dateSz int) {
 	for _, it := range all {
 		permSz = max(permSz, len(it.details.Perm))
@@ -651,7 +1206,11 @@ func (thiss *Model) renderItem(item Item, isFocused bool) string {
 			item.name += "/"
 		}
 	}
-	return style.Render(addColorByFileType(item.name, item, isFocused, item.emphasisTextIx[:]))
+	text := addColorByFileType(item.name, item, isFocused, item.emphasisTextIx)
+	if item.infoErr != nil {
+		text += term.Red(unreadableMark, false)
+	}
+	return style.Render(text)
 }
 
 func (thiss *Model) renderItemWithDetails(item Item, all []Item, isFocused bool) string {
@@ -667,59 +1226,207 @@ func (thiss *Model) renderItemWithDetails(item Item, all []Item, isFocused bool)
 		}
 		symlinkInfo = " -> " + addColorByFileType(item.linkTargetPath+dirSlash, itemTarget, false, []int{0, 0})
 	}
+	if item.infoErr != nil {
+		symlinkInfo += term.Red(" ("+unwrapPathError(item.infoErr).Error()+")", false)
+	}
 	var permSz, userSz, groupSz, sizeSz, dateSz = getDetailsSizes(all)
 	var details = term.Width(item.details.Perm, permSz) + sep +
 		term.Width(item.details.Username, userSz) + sep +
 		term.Width(item.details.Group, groupSz) + sep +
 		term.Width(item.details.Size, sizeSz) + sep +
 		term.Width(item.details.Date, dateSz) + sep
-	return term.Gray(details, false) + addColorByFileType(item.name, item, isFocused, item.emphasisTextIx[:]) + symlinkInfo
+	return term.Gray(details, false) + addColorByFileType(item.name, item, isFocused, item.emphasisTextIx) + symlinkInfo
+}
+
+// unwrapPathError removes the operation and path from err, which are redundant on the list
+func unwrapPathError(err error) error {
+	var pathErr *os.PathError
+	if errors.As(err, &pathErr) {
+		return pathErr.Err
+	}
+	return err
 }
 
 func (thiss *Model) changeMode(mode modeEnum) {
+	isListMode := thiss.mode == modeList || thiss.mode == modeSearch
+	if isListMode && mode != modeList && mode != modeSearch {
+		thiss.rememberCursor() // Restored when the view is closed
+	}
 	if mode == modeSearch {
 		thiss.mode = modeSearch
 		thiss.items = thiss.filteredItems
 		thiss.calculateColsAndRows()
 	} else if mode == modeList {
+		thiss.stopFind()
 		thiss.searchInput = ""
 		thiss.mode = modeList
 		thiss.items = thiss.dirItems
 		thiss.calculateColsAndRows()
+		if !isListMode {
+			thiss.restoreCursor()
+		}
 	} else if mode == modeEnterPath {
-		thiss.inputPath = "/"
+		thiss.startEnterPath()
 		thiss.mode = modeEnterPath
+	} else if mode == modeFind || mode == modeGrep {
+		thiss.searchInput = ""
+		thiss.mode = mode
+		thiss.calculateColsAndRows()
+	} else if mode == modeTrash {
+		thiss.searchInput = ""
+		thiss.mode = modeTrash
+		thiss.lsTrash()
+		thiss.cursorIx = 0
+		thiss.rowOffset = 0
+		thiss.calculateColsAndRows()
+	} else if mode == modeHistory {
+		thiss.searchInput = ""
+		thiss.mode = modeHistory
+		thiss.lsHistory()
+		thiss.cursorIx = 0
+		thiss.rowOffset = 0
+		thiss.calculateColsAndRows()
+	} else if mode == modeJump {
+		thiss.searchInput = ""
+		thiss.mode = modeJump
+		thiss.lsJump()
+		thiss.cursorIx = 0
+		thiss.rowOffset = 0
+		thiss.calculateColsAndRows()
+	} else if mode == modeBookmarks {
+		thiss.searchInput = ""
+		thiss.mode = modeBookmarks
+		thiss.lsBookmarks()
+		thiss.cursorIx = 0
+		thiss.rowOffset = 0
+		thiss.calculateColsAndRows()
 	}
 }
 
+// askConfirmation shows the question on the header and runs action only if the user confirms it
+func (thiss *Model) askConfirmation(question string, action func() error) {
+	thiss.confirmQuestion = question
+	thiss.confirmAction = action
+	thiss.returnMode = thiss.mode
+	thiss.mode = modeConfirm
+}
+
+// refresh reloads the items of the current view, keeping the search filter and the cursor in range
+func (thiss *Model) refresh() {
+	thiss.pruneSelection()
+	if thiss.mode == modeTrash {
+		thiss.lsTrash()
+	} else if thiss.mode == modeBookmarks {
+		thiss.lsBookmarks()
+	} else if thiss.mode == modeJump {
+		thiss.lsJump()
+	} else if thiss.mode == modeHistory {
+		thiss.lsHistory()
+	} else {
+		if err := thiss.Ls(); err != nil {
+			thiss.err = err
+		}
+		if thiss.mode == modeSearch {
+			thiss.searchFilter(thiss.searchInput)
+			thiss.items = thiss.filteredItems
+		} else if thiss.mode == modeFind || thiss.mode == modeGrep {
+			thiss.items = thiss.filteredItems
+		}
+	}
+	thiss.calculateColsAndRows()
+	thiss.fixCursor()
+}
+
+// typeSearch adds the typed runes to the search input. Any printable rune is accepted
+func (thiss *Model) typeSearch(runes []rune) {
+	for _, r := range runes {
+		if !unicode.IsPrint(r) {
+			return
+		}
+	}
+	thiss.searchInput += string(runes)
+	thiss.searchFilter(thiss.searchInput)
+	thiss.cursorIx = 0
+	thiss.rowOffset = 0
+	thiss.changeMode(modeSearch)
+}
+
+// isCaseSensitive returns true when the search input has an uppercase letter (smart-case)
+func isCaseSensitive(input string) bool {
+	for _, r := range input {
+		if unicode.IsUpper(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// runeIndex returns the rune index of the first pattern occurrence in text, or -1
+func runeIndex(text, pattern []rune, caseSensitive bool) int {
+	equal := func(a, b rune) bool {
+		return a == b || (!caseSensitive && unicode.ToLower(a) == unicode.ToLower(b))
+	}
+	for ix := 0; ix+len(pattern) <= len(text); ix++ {
+		found := true
+		for pIx := range pattern {
+			if !equal(text[ix+pIx], pattern[pIx]) {
+				found = false
+				break
+			}
+		}
+		if found {
+			return ix
+		}
+	}
+	return -1
+}
+
 func (thiss *Model) searchFilter(input string) {
 	filtered := []Item{}
+	scores := map[string]int{}
+	caseSensitive := isCaseSensitive(input)
 	for _, v := range thiss.dirItems {
-		var a = strings.ToLower(input)
-		var b = strings.ToLower(v.name)
-		foundIx := strings.Index(b, a)
-		if foundIx > -1 {
-			v.emphasisTextIx[0] = foundIx
-			v.emphasisTextIx[1] = foundIx + len(a)
+		score, positions, ok := searchMatch(input, v.name, caseSensitive)
+		if ok {
+			v.emphasisTextIx = runeSpans(v.name, positions)
+			scores[v.name] = score
 			filtered = append(filtered, v)
 		}
-		strings.Index(b, a)
 	}
 
-	// Rank names that starts with input first
-	sort.Slice(filtered, func(i, j int) bool {
-		rank := func(it Item) int {
-			if strings.HasPrefix(it.name, input) {
-				return -1
-			}
-			return 0
+	// Rank the best matches first
+	sort.SliceStable(filtered, func(i, j int) bool {
+		a, b := filtered[i].name, filtered[j].name
+		if scores[a] != scores[b] {
+			return scores[a] > scores[b]
 		}
-		return rank(filtered[i]) < rank(filtered[j])
+		return config.SEARCH_ALGORITHM == "fuzzy" && len(a) < len(b)
 	})
 
 	thiss.filteredItems = filtered
 }
 
+// searchMatch matches input on name with the configured search algorithm. It returns the match score
+// and the rune indexes of name that were matched. Substring matches score 1 for names that starts
+// with input and 0 for the others
+func searchMatch(input, name string, caseSensitive bool) (score int, positions []int, ok bool) {
+	if config.SEARCH_ALGORITHM == "fuzzy" {
+		return fuzzyMatch(input, name, caseSensitive)
+	}
+	pattern := []rune(input)
+	foundIx := runeIndex([]rune(name), pattern, caseSensitive)
+	if foundIx < 0 {
+		return 0, nil, false
+	}
+	for ix := foundIx; ix < foundIx+len(pattern); ix++ {
+		positions = append(positions, ix)
+	}
+	if foundIx == 0 {
+		score = 1
+	}
+	return score, positions, true
+}
+
 func (thiss *Model) isPathOk(path string) bool {
 	fileInfo, err := os.Stat(path)
 	if err != nil {
@@ -730,22 +1437,24 @@ func (thiss *Model) isPathOk(path string) bool {
 
 func (thiss *Model) calculateColsAndRows() {
 
-	if thiss.showDetails || thiss.mode == modeSearch {
+	if thiss.showDetails || thiss.mode == modeSearch || thiss.mode == modeTrash || thiss.mode == modeFind ||
+		thiss.mode == modeGrep || thiss.mode == modeBookmarks || thiss.mode == modeJump ||
+		thiss.mode == modeHistory {
 		thiss.cols = 1
-		thiss.colSize = thiss.width
+		thiss.colSize = thiss.listWidth()
 		thiss.rows = len(thiss.items)
 		return
 	}
 
 	maxColSize := thiss.maxItemLength() + config.FILES_SEPARATOR_SZ
-	if maxColSize >= thiss.width {
+	if maxColSize >= thiss.listWidth() {
 		thiss.cols = 1
-		thiss.colSize = thiss.width
+		thiss.colSize = thiss.listWidth()
 		thiss.rows = len(thiss.items)
 		return
 	}
-	thiss.cols = thiss.width / maxColSize
-	thiss.colSize = thiss.width / thiss.cols
+	thiss.cols = thiss.listWidth() / maxColSize
+	thiss.colSize = thiss.listWidth() / thiss.cols
 	thiss.rows = len(thiss.items) / thiss.cols
 	if len(thiss.items)%thiss.cols > 0 {
 		thiss.rows += 1
@@ -755,8 +1464,12 @@ func (thiss *Model) calculateColsAndRows() {
 func (thiss *Model) maxItemLength() int {
 	max := 0
 	for _, i := range thiss.items {
-		if len(i.name) > max {
-			max = len(i.name)
+		sz := len(i.name)
+		if i.infoErr != nil {
+			sz += len(unreadableMark)
+		}
+		if sz > max {
+			max = sz
 		}
 	}
 	return max
@@ -775,6 +1488,26 @@ func (thiss *Model) CurrentItem() Item {
 	return thiss.items[thiss.cursorIx]
 }
 
+// targetPaths returns the absolute paths that file operations act on: the selection or, when nothing
+// is selected, the focused item
+func (thiss *Model) targetPaths() []string {
+	if len(thiss.selection) > 0 {
+		return thiss.selectedPaths()
+	}
+	if len(thiss.items) == 0 || !isSelectable(thiss.CurrentItem()) {
+		return nil
+	}
+	return []string{thiss.itemPath(thiss.CurrentItem())}
+}
+
+// fixCursor keeps the cursor and the offset inside the current items
+func (thiss *Model) fixCursor() {
+	thiss.cursorIx = minMax(thiss.cursorIx, 0, max(len(thiss.items)-1, 0))
+	if !thiss.isCursorDisplayed() {
+		thiss.setOffsetToMiddleScreen()
+	}
+}
+
 func (thiss *Model) cursorRowIx() int {
 	return (thiss.cursorIx / thiss.cols)
 }
@@ -796,7 +1529,7 @@ func (thiss *Model) cursorEnter() (shouldExit bool, hasFailed bool, exitCmd stri
 
 	if curItem.name == "./" {
 		shouldExit = true
-		exitCmd = `cd "` + thiss.path + `"`
+		exitCmd = thiss.shell.cdCmd(thiss.path)
 		return
 	}
 
@@ -805,6 +1538,11 @@ func (thiss *Model) cursorEnter() (shouldExit bool, hasFailed bool, exitCmd stri
 		return
 	}
 
+	if thiss.archive != nil {
+		hasFailed = !thiss.archiveEnter()
+		return
+	}
+
 	if curItem.fileInfo == nil {
 		return
 	}
@@ -814,31 +1552,23 @@ func (thiss *Model) cursorEnter() (shouldExit bool, hasFailed bool, exitCmd stri
 		fileInfo = curItem.linkTargetInfo
 	}
 	if fileInfo.IsDir() {
-		thiss.previousPath = thiss.path
-		thiss.path = filepath.Clean(
-			filepath.Join(thiss.path, curItem.name),
-		)
-		thiss.cursorIx = 0
-		thiss.rowOffset = 0
-		thiss.Ls()
-		thiss.calculateColsAndRows()
+		previousPath := thiss.path
+		if !thiss.changeDir(filepath.Clean(filepath.Join(thiss.path, curItem.name))) {
+			hasFailed = true
+			return
+		}
+		thiss.previousPath = previousPath
+		thiss.restoreCursor()
+		thiss.recordCurrentDir()
 		return
 	}
 
 	if !fileInfo.IsDir() {
 		shouldExit = true
 		if isFileExecutable(fileInfo) {
-			var execCmd = `"./` + curItem.name + `"`
-			exitCmd = `cd "` + thiss.path + `" && ` + execCmd
-			if config.ADD_LAST_CMD_TO_HISTORY {
-				exitCmd += " && history -s " + execCmd
-			}
+			exitCmd = thiss.shell.cdAndRunCmd(thiss.path, thiss.shell.execFileCmd(curItem.name))
 		} else {
-			var execCmd = fmt.Sprintf(config.EDIT_FILE_CMD, curItem.name)
-			exitCmd = `cd "` + thiss.path + `" && ` + execCmd
-			if config.ADD_LAST_CMD_TO_HISTORY {
-				exitCmd += " && history -s '" + execCmd + "'"
-			}
+			exitCmd = thiss.shell.cdAndRunCmd(thiss.path, thiss.shell.editFileCmd(curItem.name, 1))
 		}
 		return
 	}
@@ -847,6 +1577,10 @@ func (thiss *Model) cursorEnter() (shouldExit bool, hasFailed bool, exitCmd stri
 }
 
 func (thiss *Model) goParent() {
+	if thiss.archive != nil {
+		thiss.archiveParent()
+		return
+	}
 	newPath := filepath.Clean(
 		filepath.Join(thiss.path, ".."),
 	)
@@ -854,8 +1588,10 @@ func (thiss *Model) goParent() {
 		return
 	}
 	prevName := filepath.Base(thiss.path)
-	thiss.path = newPath
-	thiss.Ls()
+	if !thiss.changeDir(newPath) {
+		return
+	}
+	thiss.recordCurrentDir()
 	thiss.calculateColsAndRows()
 	// Set cursor to the previous open folder
 	thiss.cursorIx = func(name string) (cursorFromName int) {
@@ -870,19 +1606,13 @@ func (thiss *Model) goParent() {
 }
 
 func (thiss *Model) goToPath(path string) {
-	thiss.previousPath = thiss.path
-	thiss.path = path
-	thiss.cursorIx = 0
-	thiss.rowOffset = 0
-	thiss.Ls()
-	thiss.calculateColsAndRows()
-}
-
-func (thiss *Model) toggleSelection() {
-	if thiss.items[thiss.cursorIx].fileInfo == nil {
+	previousPath := thiss.path
+	if !thiss.changeDir(path) {
 		return
 	}
-	thiss.items[thiss.cursorIx].isSelected = !thiss.items[thiss.cursorIx].isSelected
+	thiss.previousPath = previousPath
+	thiss.restoreCursor()
+	thiss.recordCurrentDir()
 }
 
 // setOffsetToMiddleScreen recalculates and set the offset, to cursor be in the middle of the screen
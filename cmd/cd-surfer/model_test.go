@@ -2,11 +2,27 @@ package main
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/andriykrefer/cdsurfer/config"
+	tea "github.com/charmbracelet/bubbletea"
 )
 
+// TestMain keeps the tests from writing on the user data, like the visited directories
+func TestMain(m *testing.M) {
+	dataHome, err := os.MkdirTemp("", "cd-surfer-test")
+	if err != nil {
+		panic(err)
+	}
+	os.Setenv("XDG_DATA_HOME", dataHome)
+	code := m.Run()
+	os.RemoveAll(dataHome)
+	os.Exit(code)
+}
+
 func TestCalculateColsAndRows(t *testing.T) {
 	config.FILES_SEPARATOR_SZ = 2
 	m := Model{
@@ -70,3 +86,100 @@ func TestCalculateColsAndRows(t *testing.T) {
 	}
 
 }
+
+func TestGoToUnreadablePathKeepsDirectory(t *testing.T) {
+	tmp := t.TempDir()
+	os.WriteFile(filepath.Join(tmp, "file"), nil, 0644)
+	m := Model{width: 80, height: 20, path: tmp}
+	m.Init()
+	m.goToPath(filepath.Join(tmp, "missing"))
+	if m.err == nil || m.path != tmp || len(m.items) != 2 {
+		t.Fail()
+	}
+	m.goToPath(filepath.Join(tmp, "file"))
+	if m.err == nil || m.path != tmp {
+		t.Fail()
+	}
+	m.View()
+}
+
+func TestLsBrokenSymlink(t *testing.T) {
+	tmp := t.TempDir()
+	os.Symlink("missing-target", filepath.Join(tmp, "broken"))
+	m := Model{width: 80, height: 20, path: tmp}
+	if err := m.Ls(); err != nil {
+		t.Fatal(err)
+	}
+	for _, it := range m.items {
+		if it.name == "broken" && (!it.linkIsBroken || it.linkTargetPath != "missing-target") {
+			t.Fail()
+		}
+	}
+}
+
+func TestTypeSearchSmartCase(t *testing.T) {
+	tmp := t.TempDir()
+	for _, name := range []string{".env", "2023-report.pdf", "Report.txt", "notes"} {
+		os.WriteFile(filepath.Join(tmp, name), nil, 0644)
+	}
+	m := Model{path: tmp, width: 80, height: 20}
+	m.Ls()
+	m.typeSearch([]rune(".e"))
+	if len(m.filteredItems) != 1 || m.filteredItems[0].name != ".env" {
+		t.Fatal(m.filteredItems)
+	}
+
+	m.searchInput = ""
+	m.typeSearch([]rune("report"))
+	if len(m.filteredItems) != 2 || m.filteredItems[0].name != "Report.txt" {
+		t.Fatal(m.filteredItems)
+	}
+	m.searchInput = ""
+	m.typeSearch([]rune("Rep"))
+	if len(m.filteredItems) != 1 || m.filteredItems[0].name != "Report.txt" {
+		t.Fatal(m.filteredItems)
+	}
+	m.searchInput = ""
+	m.typeSearch([]rune("2023-r"))
+	if len(m.filteredItems) != 1 || m.filteredItems[0].emphasisTextIx[1] != 6 {
+		t.Fatal(m.filteredItems)
+	}
+}
+
+func TestTypeSpaceOnSearch(t *testing.T) {
+	tmp := t.TempDir()
+	for _, name := range []string{"my file", "myfile"} {
+		os.WriteFile(filepath.Join(tmp, name), nil, 0644)
+	}
+	m := Model{path: tmp}
+	m.Init()
+	m.Update(tea.WindowSizeMsg{Width: 80, Height: 20})
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("my")})
+	m.Update(tea.KeyMsg{Type: tea.KeySpace})
+	if m.searchInput != "my " || len(m.selection) != 0 || len(m.items) != 1 || m.items[0].name != "my file" {
+		t.Fatal(m.searchInput, m.selection, m.items)
+	}
+}
+
+func TestLsUnreadable(t *testing.T) {
+	tmp := t.TempDir()
+	os.Mkdir(filepath.Join(tmp, "locked"), 0000)
+	t.Cleanup(func() { os.Chmod(filepath.Join(tmp, "locked"), 0755) })
+	m := Model{path: tmp, width: 80, height: 20}
+	if err := m.Ls(); err != nil {
+		t.Fatal(err)
+	}
+	if os.Geteuid() != 0 { // root reads anything
+		if m.items[1].name != "locked/" || m.items[1].infoErr == nil {
+			t.Fatal(m.items)
+		}
+	}
+	m.items[1].infoErr = os.ErrPermission
+	m.calculateColsAndRows()
+	if !strings.Contains(m.renderItem(m.items[1], true), unreadableMark) {
+		t.Fatal(m.renderItem(m.items[1], true))
+	}
+	if !strings.Contains(m.renderItemWithDetails(m.items[1], m.items, true), "permission denied") {
+		t.Fatal(m.renderItemWithDetails(m.items[1], m.items, true))
+	}
+}
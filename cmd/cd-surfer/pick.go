@@ -0,0 +1,158 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/andriykrefer/cdsurfer/config"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// pickOptions configures the file picker mode (cd-surfer pick)
+type pickOptions struct {
+	multi      bool
+	dirsOnly   bool
+	filesOnly  bool
+	null       bool
+	outputFile string
+}
+
+func parsePickArgs(args []string) (opts pickOptions, startPath string, err error) {
+	fs := flag.NewFlagSet("pick", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: cd-surfer pick [flags] [dir]\n\n"+
+			"Browse and print the absolute paths of the picked items, instead of a cd command.\n\n")
+		fs.PrintDefaults()
+	}
+	fs.BoolVar(&opts.multi, "multi", false, "allow picking multiple items (select them with space)")
+	fs.BoolVar(&opts.dirsOnly, "dirs-only", false, "only directories can be picked")
+	fs.BoolVar(&opts.filesOnly, "files-only", false, "only files can be picked")
+	fs.BoolVar(&opts.null, "null", false, "separate the paths with NUL instead of new lines")
+	fs.StringVar(&opts.outputFile, "output-file", "", "write the paths to this file instead of stdout")
+	config.RegisterFlags(fs)
+	if err = fs.Parse(args); err != nil {
+		return
+	}
+	if err = loadConfig(fs); err != nil {
+		err = fmt.Errorf("config: %w", err)
+		return
+	}
+	if opts.dirsOnly && opts.filesOnly {
+		err = fmt.Errorf("--dirs-only and --files-only cannot be used together")
+		return
+	}
+	if fs.NArg() > 1 {
+		err = fmt.Errorf("too many arguments: %s", strings.Join(fs.Args(), " "))
+		return
+	}
+	if fs.NArg() == 1 {
+		startPath, err = filepath.Abs(fs.Arg(0))
+	}
+	return
+}
+
+func runPick(args []string) int {
+	opts, startPath, err := parsePickArgs(args)
+	if err == flag.ErrHelp {
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	m := &Model{path: startPath, pick: &opts}
+	p := tea.NewProgram(m, tea.WithOutput(os.Stderr))
+	if _, err := p.Run(); err != nil {
+		panic(err)
+	}
+	if err := m.saveCursors(); err != nil {
+		fmt.Fprintln(os.Stderr, "save_cursors:", err)
+	}
+	if err := m.saveVisits(); err != nil {
+		fmt.Fprintln(os.Stderr, "frecency:", err)
+	}
+	return m.exitCode
+}
+
+// isPickable returns true if path can be picked with the current filters
+func (thiss *Model) isPickable(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	if thiss.pick.dirsOnly {
+		return info.IsDir()
+	}
+	if thiss.pick.filesOnly {
+		return !info.IsDir()
+	}
+	return true
+}
+
+// pickPaths returns the picked paths: the selection (on --multi) or the focused item. With
+// --dirs-only, when the focused item is a file, the current directory is picked
+func (thiss *Model) pickPaths() []string {
+	paths := []string{}
+	if thiss.pick.multi && len(thiss.selection) > 0 {
+		for _, p := range thiss.selectedPaths() {
+			if thiss.isPickable(p) {
+				paths = append(paths, p)
+			}
+		}
+		return paths
+	}
+	if len(thiss.items) > 0 && isSelectable(thiss.CurrentItem()) {
+		p := thiss.itemPath(thiss.CurrentItem())
+		if thiss.isPickable(p) {
+			return append(paths, p)
+		}
+	}
+	if thiss.pick.dirsOnly {
+		paths = append(paths, filepath.Clean(thiss.path))
+	}
+	return paths
+}
+
+// isFocusedOnFile returns true if the focused item is a file (or a symlink to a file)
+func (thiss *Model) isFocusedOnFile() bool {
+	if len(thiss.items) == 0 || !isSelectable(thiss.CurrentItem()) {
+		return false
+	}
+	info, err := os.Stat(thiss.itemPath(thiss.CurrentItem()))
+	return err == nil && !info.IsDir()
+}
+
+// confirmPick writes the picked paths and quits. Nothing happens if there is nothing to pick
+func (thiss *Model) confirmPick() tea.Cmd {
+	paths := thiss.pickPaths()
+	if len(paths) == 0 {
+		return nil
+	}
+	if err := writePicked(paths, *thiss.pick); err != nil {
+		thiss.err = err
+		return nil
+	}
+	return tea.Quit
+}
+
+func writePicked(paths []string, opts pickOptions) error {
+	sep := "\n"
+	if opts.null {
+		sep = "\x00"
+	}
+	var out io.Writer = os.Stdout
+	if opts.outputFile != "" {
+		f, err := os.Create(opts.outputFile)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+	_, err := io.WriteString(out, strings.Join(paths, sep)+sep)
+	return err
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPickPaths(t *testing.T) {
+	tmp := t.TempDir()
+	os.Mkdir(filepath.Join(tmp, "dir"), 0755)
+	os.WriteFile(filepath.Join(tmp, "file"), nil, 0644)
+	m := Model{path: tmp, width: 80, height: 20, pick: &pickOptions{multi: true, filesOnly: true}}
+	m.Ls()
+	m.calculateColsAndRows()
+
+	m.selectAll()
+	paths := m.pickPaths()
+	if len(paths) != 1 || paths[0] != filepath.Join(tmp, "file") {
+		t.Fatal(paths)
+	}
+
+	m.clearSelection()
+	m.pick = &pickOptions{dirsOnly: true}
+	m.setCursorToName("file")
+	paths = m.pickPaths()
+	if len(paths) != 1 || paths[0] != tmp {
+		t.Fatal(paths)
+	}
+	m.setCursorToName("dir")
+	paths = m.pickPaths()
+	if len(paths) != 1 || paths[0] != filepath.Join(tmp, "dir") {
+		t.Fatal(paths)
+	}
+}
+
+func TestWritePicked(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out")
+	err := writePicked([]string{"/a b", "/c\nd"}, pickOptions{null: true, outputFile: out})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(out)
+	if string(data) != "/a b\x00/c\nd\x00" {
+		t.Fail()
+	}
+}
+
+func TestParsePickArgs(t *testing.T) {
+	opts, start, err := parsePickArgs([]string{"--multi", "--files-only", "/tmp"})
+	if err != nil || !opts.multi || !opts.filesOnly || start != "/tmp" {
+		t.Fail()
+	}
+	if _, _, err := parsePickArgs([]string{"--files-only", "--dirs-only"}); err == nil {
+		t.Fail()
+	}
+}
@@ -0,0 +1,293 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/andriykrefer/cdsurfer/config"
+	"github.com/andriykrefer/cdsurfer/term"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// The preview pane shows the focused item on the right side of the list. It is loaded on background,
+// so moving the cursor does not wait for it
+
+const (
+	previewReadSz       = 64 * 1024 // Bytes read from text files
+	previewMaxLinks     = 40        // Longer symlink chains are probably loops
+	previewTabSz        = 4
+	previewHexGroupSz   = 4
+	previewContextLines = 3 // Lines shown above the matched line of content search results
+)
+
+type previewMsg struct {
+	key   string
+	lines []string
+}
+
+// previewTarget returns the path of the focused item and the first line to preview. ok is false if
+// nothing can be previewed
+func (thiss *Model) previewTarget() (path string, startLine int, ok bool) {
+	if len(thiss.items) == 0 || thiss.cursorIx >= len(thiss.items) {
+		return "", 0, false
+	}
+	item := thiss.CurrentItem()
+	if thiss.archive != nil {
+		if item.archivePath == "" {
+			return "", 0, false
+		}
+		return filepath.Join(thiss.archive.path, item.archivePath), 1, true
+	}
+	if item.fullPath != "" {
+		return item.fullPath, max(item.line-previewContextLines, 1), true
+	}
+	if item.fileInfo == nil {
+		return "", 0, false
+	}
+	return filepath.Join(thiss.path, item.name), 1, true
+}
+
+func (thiss *Model) isPreviewShown() bool {
+	return thiss.showPreview && thiss.mode != modeEnterPath
+}
+
+// previewWidth returns the width of the preview pane, including its border
+func (thiss *Model) previewWidth() int {
+	if !thiss.isPreviewShown() {
+		return 0
+	}
+	return thiss.width * config.PREVIEW_SIZE / 100
+}
+
+// listWidth returns the width available for the list
+func (thiss *Model) listWidth() int {
+	return max(thiss.width-thiss.previewWidth(), 1)
+}
+
+// loadPreview returns the command that loads the preview of the focused item, if it changed
+func (thiss *Model) loadPreview() tea.Cmd {
+	if !thiss.isPreviewShown() {
+		return nil
+	}
+	path, startLine, ok := thiss.previewTarget()
+	width, height := max(thiss.previewWidth()-2, 1), thiss.rowsDisplayed()
+	key := fmt.Sprintf("%s:%d:%dx%d", path, startLine, width, height)
+	if !ok {
+		key = ""
+	}
+	if key == thiss.previewKey {
+		return nil
+	}
+	thiss.previewKey = key
+	thiss.previewLines = nil
+	if !ok {
+		return nil
+	}
+	if thiss.archive != nil {
+		a, name := thiss.archive, thiss.CurrentItem().archivePath
+		return func() tea.Msg {
+			return previewMsg{key: key, lines: previewArchiveEntry(a, name, width, height)}
+		}
+	}
+	return func() tea.Msg {
+		return previewMsg{key: key, lines: buildPreview(path, startLine, width, height)}
+	}
+}
+
+func (thiss *Model) setPreview(msg previewMsg) {
+	if msg.key == thiss.previewKey {
+		thiss.previewLines = msg.lines
+	}
+}
+
+func (thiss *Model) togglePreview() {
+	thiss.showPreview = !thiss.showPreview
+	thiss.previewKey = ""
+	thiss.calculateColsAndRows()
+	thiss.fixCursor()
+}
+
+// renderPreview returns the preview pane, with height lines
+func (thiss *Model) renderPreview(height int) string {
+	width := thiss.previewWidth() - 1 // Border
+	style := lipgloss.NewStyle().
+		Width(width).MaxWidth(width).
+		Height(height).MaxHeight(height).
+		PaddingLeft(1).
+		BorderStyle(lipgloss.NormalBorder()).BorderLeft(true).
+		BorderForeground(lipgloss.Color("8"))
+	return style.Render(strings.Join(thiss.previewLines, "\n"))
+}
+
+// buildPreview returns up to height lines previewing path, with width columns at most. Directories
+// are listed, text files are shown from startLine, binary files are hex dumped and symlinks show
+// their target chain before the preview of the final target
+func buildPreview(path string, startLine, width, height int) []string {
+	lines := []string{}
+	info, err := os.Lstat(path)
+	if err != nil {
+		return []string{term.Red(truncate(unwrapPathError(err).Error(), width), false)}
+	}
+	for ix := 0; info.Mode()&os.ModeSymlink != 0; ix++ {
+		target, err := os.Readlink(path)
+		if err != nil || ix == previewMaxLinks {
+			return append(lines, term.Red(truncate("cannot resolve the link", width), false))
+		}
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(filepath.Dir(path), target)
+		}
+		lines = append(lines, term.Cyan(truncate("-> "+target, width), false))
+		path = target
+		info, err = os.Lstat(path)
+		if err != nil {
+			return append(lines, term.Red(truncate("broken link: "+unwrapPathError(err).Error(), width), false))
+		}
+	}
+	if len(lines) > 0 {
+		lines = append(lines, "")
+	}
+	height = max(height-len(lines), 0)
+
+	switch {
+	case info.IsDir():
+		return append(lines, previewDir(path, width, height)...)
+	case !info.Mode().IsRegular():
+		return append(lines, term.Gray(truncate(info.Mode().String(), width), false))
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return append(lines, term.Red(truncate(unwrapPathError(err).Error(), width), false))
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, previewReadSz))
+	if err != nil {
+		return append(lines, term.Red(truncate(unwrapPathError(err).Error(), width), false))
+	}
+	return append(lines, previewData(path, data, startLine, width, height)...)
+}
+
+// previewData returns the preview of the contents of a file: hex dump for binaries and text for the rest
+func previewData(path string, data []byte, startLine, width, height int) []string {
+	switch {
+	case len(data) == 0:
+		return []string{term.Gray("(empty)", false)}
+	case bytes.IndexByte(data[:min(len(data), grepBinaryCheckSz)], 0) >= 0:
+		return previewHex(data, width, height)
+	}
+	return previewText(data, detectLanguage(path, data), startLine, width, height)
+}
+
+// previewDir lists the directory with the same logic and colors of the list
+func previewDir(path string, width, height int) []string {
+	m := Model{path: path}
+	if err := m.Ls(); err != nil {
+		return []string{term.Red(truncate(unwrapPathError(err).Error(), width), false)}
+	}
+	lines := []string{}
+	for _, item := range m.items {
+		if len(lines) == height {
+			break
+		}
+		if item.name == "./" || item.name == "../" {
+			continue
+		}
+		name := item.name
+		if item.linkTargetInfo != nil && item.linkTargetInfo.IsDir() {
+			name += "/"
+		}
+		lines = append(lines, addColorByFileType(truncate(name, width), item, false, nil))
+	}
+	if len(lines) == 0 {
+		lines = append(lines, term.Gray("(empty)", false))
+	}
+	return lines
+}
+
+// previewText returns the lines of data from startLine, with the tabs expanded and the control
+// chars removed. The first previewHighlightSz bytes are highlighted if lang is not nil
+func previewText(data []byte, lang *language, startLine, width, height int) []string {
+	lines := []string{}
+	lex := lexer{lang: lang}
+	highlighted := 0
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, len(data)+1), len(data)+1)
+	for lineNum := 1; scanner.Scan() && len(lines) < height; lineNum++ {
+		line := sanitizeLine(scanner.Text())
+		if lang == nil || highlighted >= previewHighlightSz {
+			if lineNum >= startLine {
+				lines = append(lines, truncate(line, width))
+			}
+			continue
+		}
+		// The lines before startLine are tokenized too, since a comment or string may continue on the
+		// shown lines
+		highlighted += len(line)
+		tokens := lex.tokenize(line)
+		if lineNum >= startLine {
+			lines = append(lines, renderTokens(tokens, width))
+		}
+	}
+	return lines
+}
+
+// sanitizeLine expands the tabs and replaces the control chars and invalid UTF-8 of line
+func sanitizeLine(line string) string {
+	var b strings.Builder
+	col := 0
+	for _, r := range line {
+		switch {
+		case r == '\t':
+			n := previewTabSz - col%previewTabSz
+			b.WriteString(strings.Repeat(" ", n))
+			col += n
+			continue
+		case r < ' ' || r == 0x7f || r == utf8.RuneError:
+			r = '.'
+		}
+		b.WriteRune(r)
+		col++
+	}
+	return b.String()
+}
+
+// previewHex returns the hex dump of data, with as many bytes per line as fit width
+func previewHex(data []byte, width, height int) []string {
+	// Each byte takes 3 columns on the hex part and 1 on the text part. The offset takes 10
+	perLine := max((width-11)/4/previewHexGroupSz*previewHexGroupSz, previewHexGroupSz)
+	lines := []string{}
+	for offset := 0; offset < len(data) && len(lines) < height; offset += perLine {
+		chunk := data[offset:min(offset+perLine, len(data))]
+		hex := ""
+		text := ""
+		for ix := 0; ix < perLine; ix++ {
+			if ix >= len(chunk) {
+				hex += "   "
+				continue
+			}
+			hex += fmt.Sprintf("%02x ", chunk[ix])
+			if chunk[ix] >= ' ' && chunk[ix] < 0x7f {
+				text += string(chunk[ix])
+			} else {
+				text += "."
+			}
+		}
+		line := term.Gray(fmt.Sprintf("%08x  ", offset), false) + hex + term.Gray(text, false)
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// truncate cuts s to width runes
+func truncate(s string, width int) string {
+	if utf8.RuneCountInString(s) <= width {
+		return s
+	}
+	return string([]rune(s)[:max(width, 0)])
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/andriykrefer/cdsurfer/config"
+	"github.com/gookit/color"
+)
+
+func TestBuildPreview(t *testing.T) {
+	tmp := t.TempDir()
+	os.Mkdir(filepath.Join(tmp, "dir"), 0755)
+	os.WriteFile(filepath.Join(tmp, "dir", "a.txt"), nil, 0644)
+	os.Mkdir(filepath.Join(tmp, "dir", "sub"), 0755)
+	os.WriteFile(filepath.Join(tmp, "text"), []byte("one\n\ttwo\x1b\nthree\nfour\n"), 0644)
+	os.WriteFile(filepath.Join(tmp, "bin"), []byte("ab\x00\xffcdefgh"), 0644)
+	os.WriteFile(filepath.Join(tmp, "empty"), nil, 0644)
+	os.Symlink("text", filepath.Join(tmp, "link1"))
+	os.Symlink("link1", filepath.Join(tmp, "link2"))
+	os.Symlink("missing", filepath.Join(tmp, "broken"))
+
+	width := 40
+	preview := func(name string, startLine, height int) string {
+		return color.ClearCode(strings.Join(buildPreview(filepath.Join(tmp, name), startLine, width, height), "\n"))
+	}
+	if p := preview("dir", 1, 10); p != "sub/\na.txt" {
+		t.Errorf("%q", p)
+	}
+	if p := preview("text", 2, 2); p != "    two.\nthree" {
+		t.Errorf("%q", p)
+	}
+	if p := preview("bin", 1, 10); p != "00000000  61 62 00 ff ab..\n00000004  63 64 65 66 cdef\n00000008  67 68       gh" {
+		t.Errorf("%q", p)
+	}
+	if p := preview("empty", 1, 10); p != "(empty)" {
+		t.Errorf("%q", p)
+	}
+	width = 200
+	expected := "-> " + filepath.Join(tmp, "link1") + "\n-> " + filepath.Join(tmp, "text") + "\n\none"
+	if p := preview("link2", 1, 4); p != expected {
+		t.Errorf("%q", p)
+	}
+	if p := preview("broken", 1, 4); !strings.Contains(p, "broken link") {
+		t.Errorf("%q", p)
+	}
+}
+
+func TestPreviewLayout(t *testing.T) {
+	tmp := t.TempDir()
+	os.WriteFile(filepath.Join(tmp, "file"), []byte("content\n"), 0644)
+	previewSize := config.PREVIEW_SIZE
+	t.Cleanup(func() { config.PREVIEW_SIZE = previewSize })
+	config.PREVIEW_SIZE = 40
+	m := Model{path: tmp, width: 100, height: 20, showPreview: true}
+	m.Ls()
+	m.calculateColsAndRows()
+	if m.listWidth() != 60 || m.colSize > 60 {
+		t.Fatal(m.listWidth(), m.colSize)
+	}
+	cmd := m.loadPreview()
+	if cmd == nil || m.loadPreview() != nil { // Loaded once per item
+		t.Fatal()
+	}
+	m.setPreview(cmd().(previewMsg))
+	m.setCursorToName("file")
+	m.setPreview(m.loadPreview()().(previewMsg))
+	if len(m.previewLines) != 1 || m.previewLines[0] != "content" || !strings.Contains(m.View(), "content") {
+		t.Fatal(m.previewLines)
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// askInput shows a single line text input on the header. action runs with the typed text when the
+// user presses enter. esc cancels it
+func (thiss *Model) askInput(title, initial string, action func(input string) error) {
+	thiss.promptTitle = title
+	thiss.promptInput = initial
+	thiss.promptAction = action
+	thiss.returnMode = thiss.mode
+	thiss.mode = modePrompt
+}
+
+// askInputCmd is askInput for actions that start a command
+func (thiss *Model) askInputCmd(title, initial string, action func(input string) (tea.Cmd, error)) {
+	thiss.askInput(title, initial, func(input string) error {
+		cmd, err := action(input)
+		thiss.promptCmd = cmd
+		return err
+	})
+}
+
+func (thiss *Model) updatePrompt(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, keyEsc, keyQuitWithoutCd):
+		thiss.mode = thiss.returnMode
+
+	case key.Matches(msg, keyEnter):
+		thiss.mode = thiss.returnMode
+		thiss.err = thiss.promptAction(thiss.promptInput)
+		cmd := thiss.promptCmd
+		thiss.promptCmd = nil
+		return thiss, cmd
+
+	case key.Matches(msg, keyBackspace):
+		runes := []rune(thiss.promptInput)
+		if len(runes) > 0 {
+			thiss.promptInput = string(runes[:len(runes)-1])
+		}
+
+	case key.Matches(msg, keyClear):
+		thiss.promptInput = ""
+
+	case key.Matches(msg, keySpace):
+		thiss.promptInput += " "
+
+	case msg.Type == tea.KeyRunes:
+		thiss.promptInput += string(msg.Runes)
+	}
+	return thiss, nil
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type renameOp struct {
+	from, to string // Names relative to the directory
+}
+
+type bulkRenameMsg struct {
+	dir      string
+	oldNames []string
+	tmpFile  string
+	err      error
+}
+
+// validateNewName checks that name can be used as a file name inside a directory
+func validateNewName(name string) error {
+	if name == "" || name == "." || name == ".." {
+		return fmt.Errorf("invalid name %q", name)
+	}
+	if strings.Contains(name, "/") || strings.ContainsRune(name, 0) {
+		return fmt.Errorf("invalid name %q: it must not contain / or NUL", name)
+	}
+	return nil
+}
+
+// renameItem renames the focused item, in the current directory
+func (thiss *Model) renameItem(oldName, newName string) error {
+	if newName == oldName {
+		return nil
+	}
+	if err := validateNewName(newName); err != nil {
+		return err
+	}
+	ops, err := planRenames(thiss.path, []string{oldName}, []string{newName})
+	if err != nil {
+		return err
+	}
+	err = applyRenames(thiss.path, ops)
+	thiss.refresh()
+	thiss.setCursorToName(newName)
+	return err
+}
+
+// planRenames returns the sequence of renames that turns oldNames into newNames inside dir. Nothing
+// is touched on disk: it fails on collisions (two items with the same new name, or a new name that
+// is already taken by a file that is not being renamed) and breaks rename cycles (a->b, b->a) using
+// temporary names
+func planRenames(dir string, oldNames, newNames []string) ([]renameOp, error) {
+	if len(oldNames) != len(newNames) {
+		return nil, fmt.Errorf("expected %d names, got %d. Lines must not be added or removed", len(oldNames), len(newNames))
+	}
+	pending := map[string]string{} // from -> to
+	isOld := map[string]bool{}
+	final := map[string]string{} // new name -> old name
+	for ix, from := range oldNames {
+		to := newNames[ix]
+		if err := validateNewName(to); err != nil {
+			return nil, err
+		}
+		if other, found := final[to]; found {
+			return nil, fmt.Errorf("collision: %s and %s would both be named %s", other, from, to)
+		}
+		final[to] = from
+		isOld[from] = true
+		if from != to {
+			pending[from] = to
+		}
+	}
+	for from, to := range pending {
+		if isOld[to] {
+			continue // Will be renamed away before
+		}
+		if _, err := os.Lstat(filepath.Join(dir, to)); err == nil {
+			return nil, fmt.Errorf("collision: cannot rename %s to %s, it already exists", from, to)
+		}
+	}
+
+	ops := []renameOp{}
+	tmpCount := 0
+	for len(pending) > 0 {
+		froms := make([]string, 0, len(pending))
+		for from := range pending {
+			froms = append(froms, from)
+		}
+		sort.Strings(froms)
+		progressed := false
+		for _, from := range froms {
+			to := pending[from]
+			if _, isPendingSource := pending[to]; isPendingSource {
+				continue // The target is still taken
+			}
+			ops = append(ops, renameOp{from, to})
+			delete(pending, from)
+			progressed = true
+		}
+		if progressed {
+			continue
+		}
+		// Only cycles remain. Free the first name by moving it to a temporary one
+		from := froms[0]
+		var tmp string
+		for {
+			tmpCount++
+			tmp = ".cd-surfer-rename-" + strconv.Itoa(tmpCount)
+			_, err := os.Lstat(filepath.Join(dir, tmp))
+			_, isFinal := final[tmp]
+			if err != nil && !isOld[tmp] && !isFinal {
+				break
+			}
+		}
+		ops = append(ops, renameOp{from, tmp})
+		pending[tmp] = pending[from]
+		delete(pending, from)
+	}
+	return ops, nil
+}
+
+func applyRenames(dir string, ops []renameOp) error {
+	for _, op := range ops {
+		if err := os.Rename(filepath.Join(dir, op.from), filepath.Join(dir, op.to)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// bulkRenameNames returns the names the bulk rename acts on: the selected items of the current
+// directory or, when nothing is selected, all items on the current view. A selection that is all on
+// other folders is an error
+func (thiss *Model) bulkRenameNames() ([]string, error) {
+	names := []string{}
+	selected := thiss.selectedPaths()
+	for _, p := range selected {
+		if filepath.Dir(p) == filepath.Clean(thiss.path) {
+			names = append(names, filepath.Base(p))
+		}
+	}
+	if len(names) > 0 {
+		return names, nil
+	}
+	if len(selected) > 0 {
+		return nil, fmt.Errorf("the selection (%d items) is on other folders. Clear it to rename the items of this one", len(selected))
+	}
+	for _, it := range thiss.items {
+		if !isSelectable(it) {
+			continue
+		}
+		names = append(names, it.fileInfo.Name())
+	}
+	return names, nil
+}
+
+// bulkRename writes the names to a temporary file and opens it in $EDITOR (like vidir). The edited
+// names are applied when the editor exits
+func (thiss *Model) bulkRename() tea.Cmd {
+	names, err := thiss.bulkRenameNames()
+	if err != nil {
+		thiss.err = err
+		return nil
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	for _, n := range names {
+		if strings.Contains(n, "\n") {
+			thiss.err = fmt.Errorf("cannot bulk rename %q: it contains a new line", n)
+			return nil
+		}
+	}
+	f, err := os.CreateTemp("", "cd-surfer-rename-*.txt")
+	if err != nil {
+		thiss.err = err
+		return nil
+	}
+	_, err = f.WriteString(strings.Join(names, "\n") + "\n")
+	f.Close()
+	if err != nil {
+		os.Remove(f.Name())
+		thiss.err = err
+		return nil
+	}
+	dir := thiss.path
+	tmpFile := f.Name()
+	return tea.ExecProcess(editorCommand(tmpFile), func(err error) tea.Msg {
+		return bulkRenameMsg{dir: dir, oldNames: names, tmpFile: tmpFile, err: err}
+	})
+}
+
+func (thiss *Model) applyBulkRename(msg bulkRenameMsg) {
+	defer os.Remove(msg.tmpFile)
+	if msg.err != nil {
+		thiss.err = msg.err
+		return
+	}
+	data, err := os.ReadFile(msg.tmpFile)
+	if err != nil {
+		thiss.err = err
+		return
+	}
+	newNames := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	ops, err := planRenames(msg.dir, msg.oldNames, newNames)
+	if err != nil {
+		thiss.err = err
+		return
+	}
+	thiss.err = applyRenames(msg.dir, ops)
+	thiss.refresh()
+}
+
+// editorCommand returns the command that opens file on the user editor ($VISUAL, $EDITOR or nano)
+func editorCommand(file string) *exec.Cmd {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	args := strings.Fields(editor)
+	if len(args) == 0 {
+		args = []string{"nano"}
+	}
+	return exec.Command(args[0], append(args[1:], file)...)
+}
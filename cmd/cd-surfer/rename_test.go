@@ -0,0 +1,110 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestPlanRenames(t *testing.T) {
+	tmp := t.TempDir()
+	for _, n := range []string{"a", "b", "c", "other"} {
+		os.WriteFile(filepath.Join(tmp, n), []byte(n), 0644)
+	}
+
+	// Swap a and b
+	ops, err := planRenames(tmp, []string{"a", "b"}, []string{"b", "a"})
+	if err != nil || len(ops) != 3 {
+		t.Fatal(ops, err)
+	}
+	if err := applyRenames(tmp, ops); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(filepath.Join(tmp, "a"))
+	if string(data) != "b" {
+		t.Fail()
+	}
+
+	// Chain: c must be renamed before b takes its name
+	ops, err = planRenames(tmp, []string{"b", "c"}, []string{"c", "d"})
+	if err != nil || len(ops) != 2 || ops[0] != (renameOp{"c", "d"}) {
+		t.Fatal(ops, err)
+	}
+
+	// Two items with the same new name
+	if _, err := planRenames(tmp, []string{"a", "b"}, []string{"x", "x"}); err == nil {
+		t.Fail()
+	}
+	// New name taken by a file that is not being renamed
+	if _, err := planRenames(tmp, []string{"a"}, []string{"other"}); err == nil {
+		t.Fail()
+	}
+	// Lines removed
+	if _, err := planRenames(tmp, []string{"a", "b"}, []string{"a"}); err == nil {
+		t.Fail()
+	}
+	if _, err := planRenames(tmp, []string{"a"}, []string{"x/y"}); err == nil {
+		t.Fail()
+	}
+}
+
+func TestBulkRenameNames(t *testing.T) {
+	tmp := t.TempDir()
+	for _, name := range []string{"a", "b"} {
+		os.WriteFile(filepath.Join(tmp, name), nil, 0644)
+	}
+	m := Model{}
+	m.Init()
+	m.path = tmp
+	m.refresh()
+	names, err := m.bulkRenameNames()
+	if err != nil || len(names) != 2 {
+		t.Fatal(names, err)
+	}
+	m.selection = map[string]int64{filepath.Join(tmp, "b"): 0}
+	names, err = m.bulkRenameNames()
+	if err != nil || len(names) != 1 || names[0] != "b" {
+		t.Fatal(names, err)
+	}
+	m.selection = map[string]int64{"/elsewhere/c": 0}
+	if names, err = m.bulkRenameNames(); err == nil {
+		t.Fatal(names)
+	}
+}
+
+func TestRenameKeyNeedsFocusedItem(t *testing.T) {
+	tmp := t.TempDir()
+	os.WriteFile(filepath.Join(tmp, "a"), nil, 0644)
+	m := Model{}
+	m.Init()
+	m.path = tmp
+	m.Update(tea.WindowSizeMsg{Width: 80, Height: 20})
+	m.refresh()
+	m.setCursorToName("a")
+	m.Update(tea.KeyMsg{Type: tea.KeySpace})
+	if len(m.selection) != 1 {
+		t.Fatal(m.selection)
+	}
+
+	// On ../
+	m.cursorIx = 0
+	if m.CurrentItem().name != "../" {
+		t.Fatal(m.CurrentItem().name)
+	}
+	m.Update(tea.KeyMsg{Type: tea.KeyF2})
+	if m.mode != modeList {
+		t.Fatal(m.mode)
+	}
+
+	// On a search that matches nothing
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("zzz")})
+	if m.mode != modeSearch || len(m.items) != 0 {
+		t.Fatal(m.mode, m.items)
+	}
+	m.Update(tea.KeyMsg{Type: tea.KeyF2})
+	if m.mode != modeSearch {
+		t.Fatal(m.mode)
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/dustin/go-humanize"
+)
+
+const sizeUnknown = -1
+
+type dirSizeMsg struct {
+	path string
+	size int64
+}
+
+// isSelectable returns true for items that file operations can act on
+func isSelectable(item Item) bool {
+	return item.fileInfo != nil && item.name != "../" && item.fullPath == "" && item.archivePath == ""
+}
+
+// toggleSelection selects or unselects the focused item. Folders sizes are calculated in background
+func (thiss *Model) toggleSelection() tea.Cmd {
+	if len(thiss.items) == 0 || !isSelectable(thiss.CurrentItem()) {
+		return nil
+	}
+	path := thiss.itemPath(thiss.CurrentItem())
+	if _, found := thiss.selection[path]; found {
+		delete(thiss.selection, path)
+		thiss.refreshMarks()
+		return nil
+	}
+	cmd := thiss.selectItem(thiss.CurrentItem())
+	thiss.refreshMarks()
+	return cmd
+}
+
+func (thiss *Model) selectItem(item Item) tea.Cmd {
+	if thiss.selection == nil {
+		thiss.selection = map[string]int64{}
+	}
+	path := thiss.itemPath(item)
+	if !item.fileInfo.IsDir() {
+		thiss.selection[path] = item.fileInfo.Size()
+		return nil
+	}
+	thiss.selection[path] = sizeUnknown
+	return func() tea.Msg {
+		return dirSizeMsg{path: path, size: dirSize(path)}
+	}
+}
+
+// selectAll selects all the items on the current view (the search results, on search mode)
+func (thiss *Model) selectAll() tea.Cmd {
+	cmds := []tea.Cmd{}
+	for _, it := range thiss.items {
+		if !isSelectable(it) {
+			continue
+		}
+		if _, found := thiss.selection[thiss.itemPath(it)]; !found {
+			cmds = append(cmds, thiss.selectItem(it))
+		}
+	}
+	thiss.refreshMarks()
+	return tea.Batch(cmds...)
+}
+
+// invertSelection inverts the selection of the items on the current view
+func (thiss *Model) invertSelection() tea.Cmd {
+	cmds := []tea.Cmd{}
+	for _, it := range thiss.items {
+		if !isSelectable(it) {
+			continue
+		}
+		path := thiss.itemPath(it)
+		if _, found := thiss.selection[path]; found {
+			delete(thiss.selection, path)
+		} else {
+			cmds = append(cmds, thiss.selectItem(it))
+		}
+	}
+	thiss.refreshMarks()
+	return tea.Batch(cmds...)
+}
+
+func (thiss *Model) clearSelection() {
+	thiss.selection = nil
+	thiss.refreshMarks()
+}
+
+// selectedPaths returns the selected paths, sorted
+func (thiss *Model) selectedPaths() []string {
+	paths := make([]string, 0, len(thiss.selection))
+	for p := range thiss.selection {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// pruneSelection unselects paths that do not exist anymore
+func (thiss *Model) pruneSelection() {
+	for p := range thiss.selection {
+		if _, err := os.Lstat(p); err != nil {
+			delete(thiss.selection, p)
+		}
+	}
+}
+
+// selectionSummary returns the number of selected items and their total size, like "3 selected, 12 MB"
+func (thiss *Model) selectionSummary() string {
+	var total uint64
+	pending := false
+	for _, sz := range thiss.selection {
+		if sz == sizeUnknown {
+			pending = true
+			continue
+		}
+		total += uint64(sz)
+	}
+	s := humanize.Comma(int64(len(thiss.selection))) + " selected, " + humanize.Bytes(total)
+	if pending {
+		s += "+"
+	}
+	return s
+}
+
+// dirSize returns the size of all files inside path, recursively. Unreadable entries are skipped
+func dirSize(path string) int64 {
+	var total int64
+	filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.Type().IsRegular() {
+			if info, err := d.Info(); err == nil {
+				total += info.Size()
+			}
+		}
+		return nil
+	})
+	return total
+}
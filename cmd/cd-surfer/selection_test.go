@@ -0,0 +1,39 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSelectionSurvivesLs(t *testing.T) {
+	tmp := t.TempDir()
+	os.Mkdir(filepath.Join(tmp, "sub"), 0755)
+	os.WriteFile(filepath.Join(tmp, "sub", "big"), make([]byte, 1000), 0644)
+	os.WriteFile(filepath.Join(tmp, "a.txt"), []byte("12345"), 0644)
+	m := Model{path: tmp, width: 80, height: 20}
+	m.Ls()
+	m.calculateColsAndRows()
+
+	m.selectAll()
+	if len(m.selection) != 2 || m.selection[filepath.Join(tmp, "sub")] != sizeUnknown {
+		t.Fatal(m.selection)
+	}
+	m.Update(dirSizeMsg{path: filepath.Join(tmp, "sub"), size: dirSize(filepath.Join(tmp, "sub"))})
+	if m.selectionSummary() != "2 selected, 1.0 kB" {
+		t.Fatal(m.selectionSummary())
+	}
+
+	m.goToPath(filepath.Join(tmp, "sub"))
+	m.goParent()
+	for _, it := range m.items {
+		if isSelectable(it) && !it.isSelected {
+			t.Fail()
+		}
+	}
+
+	m.invertSelection()
+	if len(m.selection) != 0 || len(m.targetPaths()) != 1 {
+		t.Fail()
+	}
+}
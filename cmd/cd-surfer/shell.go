@@ -0,0 +1,205 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/andriykrefer/cdsurfer/config"
+)
+
+// shellEnum is the shell that evaluates the cd-surfer output. Each one has its own syntax for the
+// emitted commands and its own wrapper function (cd-surfer init <shell>)
+type shellEnum int
+
+const (
+	shellBash shellEnum = 0
+	shellZsh  shellEnum = 1
+	shellFish shellEnum = 2
+	shellNu   shellEnum = 3
+	shellPwsh shellEnum = 4
+)
+
+var shellNames = []string{"bash", "zsh", "fish", "nu", "pwsh"}
+
+func shellByName(name string) (shellEnum, error) {
+	for ix, n := range shellNames {
+		if n == name {
+			return shellEnum(ix), nil
+		}
+	}
+	return shellBash, fmt.Errorf("unsupported shell %q. Supported shells: %s", name, strings.Join(shellNames, ", "))
+}
+
+func (sh shellEnum) String() string {
+	return shellNames[sh]
+}
+
+// quote returns s as a single literal word for the shell, so nothing in it ($, `, quotes, ...) is
+// interpreted. Nushell runs the commands with sh, so it uses POSIX quoting
+func (sh shellEnum) quote(s string) string {
+	switch sh {
+	case shellFish:
+		// Inside fish single quotes, only \ and \' are escapes
+		s = strings.ReplaceAll(s, `\`, `\\`)
+		return "'" + strings.ReplaceAll(s, "'", `\'`) + "'"
+	case shellPwsh:
+		// PowerShell also takes the typographic single quotes as quotes. They are escaped by doubling
+		var b strings.Builder
+		b.WriteString("'")
+		for _, r := range s {
+			if strings.ContainsRune("'\u2018\u2019\u201a\u201b", r) {
+				b.WriteRune(r)
+			}
+			b.WriteRune(r)
+		}
+		b.WriteString("'")
+		return b.String()
+	}
+	// POSIX: nothing is special inside single quotes, and a single quote is written as '\''
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// cdCmd returns the command that changes the shell directory to dir
+func (sh shellEnum) cdCmd(dir string) string {
+	switch sh {
+	case shellNu:
+		return nuRecord(map[string]interface{}{"cd": dir})
+	case shellPwsh:
+		return "Set-Location -LiteralPath " + sh.quote(dir)
+	}
+	return "cd " + sh.quote(dir)
+}
+
+// editFileCmd returns the command that opens the file name on the editor (config.EDIT_FILE_CMD), at
+// the given line. Relative names get a ./ prefix, so names like -x or +cmd are not editor options
+func (sh shellEnum) editFileCmd(name string, line int) string {
+	if !filepath.IsAbs(name) {
+		name = "./" + name
+	}
+	cmd := strings.ReplaceAll(config.EditFileCmd(), "%l", strconv.Itoa(line))
+	return strings.ReplaceAll(cmd, "%s", sh.quote(name))
+}
+
+// execFileCmd returns the command that executes the file name, of the current directory
+func (sh shellEnum) execFileCmd(name string) string {
+	if sh == shellPwsh {
+		return "& " + sh.quote("./"+name)
+	}
+	return sh.quote("./" + name)
+}
+
+// cdAndRunCmd returns the command that changes the shell directory to dir and runs cmd. cmd is also
+// added to the shell history, if configured
+func (sh shellEnum) cdAndRunCmd(dir, cmd string) string {
+	addHistory := config.ADD_LAST_CMD_TO_HISTORY
+	switch sh {
+	case shellNu:
+		// Nushell cannot eval strings: the wrapper reads this record and runs cmd with sh
+		return nuRecord(map[string]interface{}{"cd": dir, "run": cmd, "history": addHistory})
+	case shellPwsh:
+		out := sh.cdCmd(dir) + "; if ($?) { " + cmd
+		if addHistory {
+			out += "; [Microsoft.PowerShell.PSConsoleReadLine]::AddToHistory(" + sh.quote(cmd) + ")"
+		}
+		return out + " }"
+	case shellFish:
+		out := sh.cdCmd(dir) + "; and " + cmd
+		if addHistory {
+			out += "; and builtin history append -- " + sh.quote(cmd) + " 2>/dev/null"
+		}
+		return out
+	case shellZsh:
+		out := sh.cdCmd(dir) + " && " + cmd
+		if addHistory {
+			out += " && print -rs -- " + sh.quote(cmd)
+		}
+		return out
+	}
+	out := sh.cdCmd(dir) + " && " + cmd
+	if addHistory {
+		out += " && history -s " + sh.quote(cmd)
+	}
+	return out
+}
+
+func nuRecord(fields map[string]interface{}) string {
+	// JSON is valid NUON
+	data, _ := json.Marshal(fields)
+	return string(data)
+}
+
+// initScript returns the wrapper function `cds` for the shell. It must be evaluated by the shell
+// config file (e.g. eval "$(cd-surfer init bash)" on ~/.bashrc)
+func (sh shellEnum) initScript() string {
+	switch sh {
+	case shellZsh:
+		return `function cds {
+  eval "$(command cd-surfer --shell zsh "$@")"
+}
+`
+	case shellFish:
+		return `function cds
+    eval (command cd-surfer --shell fish $argv | string collect)
+end
+`
+	case shellNu:
+		return `def --env cds [...args] {
+    let out = (^cd-surfer --shell nu ...$args)
+    if ($out | is-empty) { return }
+    let r = ($out | from nuon)
+    cd $r.cd
+    if ($r.run? | is-empty) { return }
+    if $r.history and ($env.config.history.file_format? == "plaintext") {
+        $r.run + "\n" | save --append $nu.history-path
+    }
+    ^sh -c $r.run
+}
+`
+	case shellPwsh:
+		return `function cds {
+    $out = & cd-surfer --shell pwsh @args
+    if ($out) { Invoke-Expression ($out -join "` + "`" + `n") }
+}
+`
+	}
+	return `function cds {
+  eval "$(command cd-surfer --shell bash "$@")"
+}
+`
+}
+
+func runInit(args []string) int {
+	fs := flag.NewFlagSet("init", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: cd-surfer init [flags] <%s>\n\n"+
+			"Prints the cds wrapper function for the shell.\n\n", strings.Join(shellNames, "|"))
+		fs.PrintDefaults()
+	}
+	cwdFile := fs.Bool("cwd-file", false, "use a wrapper that reads the directory from a temp file, instead of evaluating the output")
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+	sh, err := shellByName(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if *cwdFile {
+		fmt.Print(sh.cwdFileInitScript())
+	} else {
+		fmt.Print(sh.initScript())
+	}
+	return 0
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/andriykrefer/cdsurfer/config"
+)
+
+// Names that break naive quoting or run code when evaluated
+var adversarialNames = []string{
+	`dq"name`,
+	`sq'name`,
+	`$(touch PWNED)`,
+	"`touch PWNED`",
+	`$HOME`,
+	`a\b\'c`,
+	`x'; touch PWNED; echo '`,
+	`x"; touch PWNED; echo "`,
+	"new\nline",
+	`-rf *`,
+	`!! && ;|&<>`,
+	"‘typographic’",
+	`-x`,
+	`+!touch PWNED`,
+}
+
+func TestShellByName(t *testing.T) {
+	for _, name := range shellNames {
+		sh, err := shellByName(name)
+		if err != nil || sh.String() != name {
+			t.Fail()
+		}
+		if !strings.Contains(sh.initScript(), "--shell "+name) {
+			t.Error(name)
+		}
+	}
+	if _, err := shellByName("tcsh"); err == nil {
+		t.Fail()
+	}
+}
+
+func TestCdCmd(t *testing.T) {
+	if shellBash.cdCmd("/tmp/a b") != `cd '/tmp/a b'` {
+		t.Fail()
+	}
+	if shellPwsh.cdCmd("/tmp") != `Set-Location -LiteralPath '/tmp'` {
+		t.Fail()
+	}
+	if shellNu.cdAndRunCmd("/tmp", "./x") != `{"cd":"/tmp","history":true,"run":"./x"}` {
+		t.Fail()
+	}
+}
+
+func TestQuote(t *testing.T) {
+	if shellFish.quote(`a\'b`) != `'a\\\'b'` {
+		t.Fail()
+	}
+	if shellPwsh.quote("it's ‘x’") != "'it''s ‘‘x’’'" {
+		t.Fail()
+	}
+}
+
+// TestEvalAdversarialNames evaluates the emitted commands on real shells and checks that the
+// directory is changed, the file is run with its name intact, and nothing else is executed
+func TestEvalAdversarialNames(t *testing.T) {
+	shells := map[shellEnum][]string{
+		shellBash: {"bash", "-c", `eval "$1"; echo; pwd`, "_"},
+		shellZsh:  {"zsh", "-c", `eval "$1"; echo; pwd`, "_"},
+		shellFish: {"fish", "-c", `eval $argv[1]; echo; pwd`},
+		// Nushell runs the commands with sh
+		shellNu: {"sh", "-c", `eval "$1"; echo; pwd`, "_"},
+	}
+	for sh, argv := range shells {
+		if _, err := exec.LookPath(argv[0]); err != nil {
+			t.Logf("%s not found, skipping", argv[0])
+			continue
+		}
+		for _, name := range adversarialNames {
+			tmp := t.TempDir()
+			dir := filepath.Join(tmp, name)
+			if err := os.Mkdir(dir, 0755); err != nil {
+				t.Fatal(err)
+			}
+			// The script prints its own name
+			os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\nprintf '%s' \"$0\"\n"), 0755)
+
+			var cmd string
+			if sh == shellNu {
+				cmd = "cd " + sh.quote(dir) + " && " + sh.execFileCmd(name)
+			} else {
+				cmd = sh.cdAndRunCmd(dir, sh.execFileCmd(name))
+			}
+			out, err := exec.Command(argv[0], append(argv[1:], cmd)...).CombinedOutput()
+			if err != nil {
+				t.Errorf("%s: %q: %v: %s", sh, name, err, out)
+				continue
+			}
+			want := "./" + name + "\n" + dir + "\n"
+			if string(out) != want {
+				t.Errorf("%s: %q: got %q, want %q", sh, name, out, want)
+			}
+			matches, _ := filepath.Glob(filepath.Join(tmp, "*", "PWNED"))
+			if _, err := os.Stat("PWNED"); err == nil || len(matches) > 0 {
+				os.Remove("PWNED")
+				t.Errorf("%s: %q: injected command was executed", sh, name)
+			}
+		}
+	}
+}
+
+func TestEditFileCmd(t *testing.T) {
+	if shellBash.editFileCmd(`$(x)".txt`, 1) != `nano +1 './$(x)".txt'` {
+		t.Fail()
+	}
+	if shellBash.editFileCmd("%l.txt", 12) != `nano +12 './%l.txt'` {
+		t.Fail()
+	}
+	if shellBash.editFileCmd("/tmp/a.txt", 1) != `nano +1 '/tmp/a.txt'` {
+		t.Fail()
+	}
+}
+
+// TestEditFileCmdAdversarialNames runs the editor command with an editor that prints its arguments,
+// and checks that the name is passed intact and is never taken as an option
+func TestEditFileCmdAdversarialNames(t *testing.T) {
+	defer func(cmd string) { config.EDIT_FILE_CMD = cmd }(config.EDIT_FILE_CMD)
+	editor := filepath.Join(t.TempDir(), "editor")
+	os.WriteFile(editor, []byte("#!/bin/sh\nfor a; do printf '[%s]' \"$a\"; done\n"), 0755)
+	config.EDIT_FILE_CMD = "vim +%l %s"
+	if cmd := shellBash.editFileCmd("+!touch PWNED", 3); cmd != `vim +3 './+!touch PWNED'` {
+		t.Error(cmd)
+	}
+	// The quotes around %s, like on the old default nano "%s", are removed
+	for _, template := range []string{" +%l %s", ` +%l "%s"`, ` +%l '%s'`} {
+		config.EDIT_FILE_CMD = editor + template
+		for _, name := range adversarialNames {
+			tmp := t.TempDir()
+			c := exec.Command("sh", "-c", shellBash.editFileCmd(name, 1))
+			c.Dir = tmp
+			out, err := c.CombinedOutput()
+			if err != nil {
+				t.Errorf("%s: %q: %v: %s", template, name, err, out)
+				continue
+			}
+			if want := "[+1][./" + name + "]"; string(out) != want {
+				t.Errorf("%s: %q: got %q, want %q", template, name, out, want)
+			}
+			if _, err := os.Stat(filepath.Join(tmp, "PWNED")); err == nil {
+				t.Errorf("%s: %q: injected command was executed", template, name)
+			}
+		}
+	}
+}
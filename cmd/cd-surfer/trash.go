@@ -0,0 +1,366 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/andriykrefer/cdsurfer/config"
+)
+
+// Trash implementation following the freedesktop.org Trash specification
+// https://specifications.freedesktop.org/trash-spec/trashspec-latest.html
+
+const trashInfoExt = ".trashinfo"
+const trashDateFormat = "2006-01-02T15:04:05"
+
+// trashDir returns the home trash
+func trashDir() string {
+	return filepath.Join(config.DataHome(), "Trash")
+}
+
+func trashFilesDir(trash string) string {
+	return filepath.Join(trash, "files")
+}
+
+func trashInfoDir(trash string) string {
+	return filepath.Join(trash, "info")
+}
+
+// trashDirs returns the home trash and the trashes of the user on the top directories of the mounted
+// filesystems that exist
+func trashDirs() []string {
+	dirs := []string{trashDir()}
+	seen := map[string]bool{trashDir(): true}
+	uid := strconv.Itoa(os.Getuid())
+	for _, mount := range mountPoints() {
+		for _, dir := range []string{filepath.Join(mount, ".Trash", uid), filepath.Join(mount, ".Trash-"+uid)} {
+			// A folder can be mounted more than once
+			if info, err := os.Lstat(dir); err == nil && info.IsDir() && !seen[dir] {
+				seen[dir] = true
+				dirs = append(dirs, dir)
+			}
+		}
+	}
+	return dirs
+}
+
+// mountPoints returns the mount points of /proc/self/mounts. It is empty where it does not exist
+func mountPoints() []string {
+	data, err := os.ReadFile("/proc/self/mounts")
+	if err != nil {
+		return nil
+	}
+	// Spaces and other characters of the mount points are escaped as octal, like \040
+	unescape := strings.NewReplacer(`\040`, " ", `\011`, "\t", `\012`, "\n", `\134`, `\`)
+	mounts := []string{}
+	for _, line := range strings.Split(string(data), "\n") {
+		if fields := strings.Fields(line); len(fields) >= 2 {
+			mounts = append(mounts, unescape.Replace(fields[1]))
+		}
+	}
+	return mounts
+}
+
+// deviceOf returns the device of path, or of its nearest parent that exists
+func deviceOf(path string) (uint64, error) {
+	for {
+		info, err := os.Stat(path)
+		if err == nil {
+			st, ok := info.Sys().(*syscall.Stat_t)
+			if !ok {
+				return 0, fmt.Errorf("cannot read the device of %s", path)
+			}
+			return uint64(st.Dev), nil
+		}
+		if !errors.Is(err, os.ErrNotExist) || path == filepath.Dir(path) {
+			return 0, err
+		}
+		path = filepath.Dir(path)
+	}
+}
+
+// mountTopDir returns the top directory of the mounted filesystem of path, which is on device dev
+func mountTopDir(path string, dev uint64) string {
+	dir := filepath.Dir(path)
+	for dir != filepath.Dir(dir) {
+		if parentDev, err := deviceOf(filepath.Dir(dir)); err != nil || parentDev != dev {
+			break
+		}
+		dir = filepath.Dir(dir)
+	}
+	return dir
+}
+
+// trashDirFor returns the trash for path and, if it is not the home trash, the top directory it is on.
+// Files on other filesystems than the home trash are moved to $topdir/.Trash/$uid (if the administrator
+// created $topdir/.Trash) or to $topdir/.Trash-$uid, so they are not copied. The home trash is used if
+// none of them can be created
+func trashDirFor(path string) (trash, topDir string) {
+	dev, err := deviceOf(filepath.Dir(path))
+	homeDev, homeErr := deviceOf(trashDir())
+	if err != nil || homeErr != nil || dev == homeDev {
+		return trashDir(), ""
+	}
+	topDir = mountTopDir(path, dev)
+	uid := strconv.Itoa(os.Getuid())
+	// $topdir/.Trash must be a sticky folder, not a symlink
+	if info, err := os.Lstat(filepath.Join(topDir, ".Trash")); err == nil && info.IsDir() && info.Mode()&os.ModeSticky != 0 {
+		if trash := filepath.Join(topDir, ".Trash", uid); makeTopDirTrash(trash) == nil {
+			return trash, topDir
+		}
+	}
+	if trash := filepath.Join(topDir, ".Trash-"+uid); makeTopDirTrash(trash) == nil {
+		return trash, topDir
+	}
+	return trashDir(), ""
+}
+
+// makeTopDirTrash creates a trash on a top directory. An existing one must be a folder of the user, not
+// a symlink
+func makeTopDirTrash(trash string) error {
+	if err := os.Mkdir(trash, 0700); err != nil && !errors.Is(err, os.ErrExist) {
+		return err
+	}
+	info, err := os.Lstat(trash)
+	if err != nil {
+		return err
+	}
+	if st, ok := info.Sys().(*syscall.Stat_t); !info.IsDir() || !ok || int(st.Uid) != os.Getuid() {
+		return fmt.Errorf("%s is not a folder of the user", trash)
+	}
+	if err := os.MkdirAll(trashFilesDir(trash), 0700); err != nil {
+		return err
+	}
+	return os.MkdirAll(trashInfoDir(trash), 0700)
+}
+
+// trashTopDir returns the directory that the relative paths of a trash info are relative to
+func trashTopDir(trash string) string {
+	if filepath.Base(filepath.Dir(trash)) == ".Trash" {
+		return filepath.Dir(filepath.Dir(trash))
+	}
+	return filepath.Dir(trash)
+}
+
+// moveToTrash moves path to its trash, writing its .trashinfo file
+func moveToTrash(path string) error {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	trash, topDir := trashDirFor(path)
+	return moveToTrashDir(path, trash, topDir)
+}
+
+// moveToTrashDir moves path to trash. On trashes of top directories (topDir != ""), the original path is
+// saved relative to topDir
+func moveToTrashDir(path, trash, topDir string) error {
+	if err := os.MkdirAll(trashFilesDir(trash), 0700); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(trashInfoDir(trash), 0700); err != nil {
+		return err
+	}
+	originalPath := path
+	if topDir != "" {
+		if rel, err := filepath.Rel(topDir, path); err == nil {
+			originalPath = rel
+		}
+	}
+	info := "[Trash Info]\n" +
+		"Path=" + (&url.URL{Path: originalPath}).EscapedPath() + "\n" +
+		"DeletionDate=" + time.Now().Format(trashDateFormat) + "\n"
+
+	// The info file is created first, with O_EXCL, to reserve the name in the trash
+	base := filepath.Base(path)
+	name := base
+	for n := 2; ; n++ {
+		f, err := os.OpenFile(filepath.Join(trashInfoDir(trash), name+trashInfoExt), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if errors.Is(err, os.ErrExist) {
+			name = base + "." + strconv.Itoa(n)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		_, err = f.WriteString(info)
+		f.Close()
+		if err != nil {
+			os.Remove(filepath.Join(trashInfoDir(trash), name+trashInfoExt))
+			return err
+		}
+		break
+	}
+	if err := movePath(path, filepath.Join(trashFilesDir(trash), name)); err != nil {
+		os.Remove(filepath.Join(trashInfoDir(trash), name+trashInfoExt))
+		return err
+	}
+	return nil
+}
+
+type trashEntry struct {
+	trash        string // Trash folder
+	name         string // Name inside the trash "files" folder
+	originalPath string
+	deletionDate time.Time
+}
+
+func readTrashInfo(trash, name string) (entry trashEntry, err error) {
+	f, err := os.Open(filepath.Join(trashInfoDir(trash), name+trashInfoExt))
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	entry.trash = trash
+	entry.name = name
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		k, v, found := strings.Cut(scanner.Text(), "=")
+		if !found {
+			continue
+		}
+		switch k {
+		case "Path":
+			entry.originalPath, err = url.PathUnescape(v)
+			if err != nil {
+				return
+			}
+			if entry.originalPath != "" && !filepath.IsAbs(entry.originalPath) {
+				entry.originalPath = filepath.Join(trashTopDir(trash), entry.originalPath)
+			}
+		case "DeletionDate":
+			entry.deletionDate, _ = time.ParseInLocation(trashDateFormat, v, time.Local)
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return
+	}
+	if entry.originalPath == "" {
+		err = fmt.Errorf("invalid trash info for %s", name)
+	}
+	return
+}
+
+// listTrash returns the entries of the trashes, the most recently deleted first
+func listTrash(trashes []string) ([]trashEntry, error) {
+	entries := []trashEntry{}
+	for _, trash := range trashes {
+		infos, err := os.ReadDir(trashInfoDir(trash))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range infos {
+			if !strings.HasSuffix(f.Name(), trashInfoExt) {
+				continue
+			}
+			entry, err := readTrashInfo(trash, strings.TrimSuffix(f.Name(), trashInfoExt))
+			if err != nil {
+				continue
+			}
+			entries = append(entries, entry)
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].deletionDate.After(entries[j].deletionDate)
+	})
+	return entries, nil
+}
+
+// restoreFromTrash moves a trash entry back to its original path. It fails if the original path is
+// taken
+func restoreFromTrash(trash, name string) (string, error) {
+	entry, err := readTrashInfo(trash, name)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Lstat(entry.originalPath); err == nil {
+		return "", fmt.Errorf("cannot restore: %s already exists", entry.originalPath)
+	}
+	if err := os.MkdirAll(filepath.Dir(entry.originalPath), 0755); err != nil {
+		return "", err
+	}
+	if err := movePath(filepath.Join(trashFilesDir(trash), name), entry.originalPath); err != nil {
+		return "", err
+	}
+	return entry.originalPath, os.Remove(filepath.Join(trashInfoDir(trash), name+trashInfoExt))
+}
+
+// purgeFromTrash permanently deletes a trash entry
+func purgeFromTrash(trash, name string) error {
+	if err := os.RemoveAll(filepath.Join(trashFilesDir(trash), name)); err != nil {
+		return err
+	}
+	return os.Remove(filepath.Join(trashInfoDir(trash), name+trashInfoExt))
+}
+
+// trashOfItem returns the trash and the name of an item of the trash view
+func trashOfItem(item Item) (trash, name string) {
+	return filepath.Dir(filepath.Dir(item.fullPath)), filepath.Base(item.fullPath)
+}
+
+// lsTrash fills the view with the trash entries
+func (thiss *Model) lsTrash() {
+	entries, err := listTrash(trashDirs())
+	if err != nil {
+		thiss.err = err
+	}
+	items := []Item{}
+	for _, e := range entries {
+		fullPath := filepath.Join(trashFilesDir(e.trash), e.name)
+		info, err := os.Lstat(fullPath)
+		if err != nil {
+			continue
+		}
+		name := e.originalPath
+		if info.IsDir() {
+			name += "/"
+		}
+		var perm, username, group, size, _ string = getDetails(info)
+		items = append(items, Item{
+			name:     name,
+			fileInfo: info,
+			fullPath: fullPath,
+			details: ItemDetails{
+				Perm:     perm,
+				Username: username,
+				Group:    group,
+				Size:     size,
+				Date:     e.deletionDate.Format("02 Jan 06 15:04"),
+			},
+		})
+	}
+	thiss.items = items
+}
+
+// trashTargets moves the selected items (or the focused one) to the trash
+func (thiss *Model) trashTargets() {
+	for _, p := range thiss.targetPaths() {
+		if err := moveToTrash(p); err != nil {
+			thiss.err = err
+			break
+		}
+	}
+	thiss.refresh()
+}
+
+// deletePaths permanently deletes paths
+func deletePaths(paths []string) error {
+	for _, p := range paths {
+		if err := os.RemoveAll(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestTrashAndRestore(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", filepath.Join(tmp, "data"))
+	file := filepath.Join(tmp, "my file%.txt")
+	os.WriteFile(file, []byte("content"), 0644)
+
+	if err := moveToTrash(file); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Lstat(file); err == nil {
+		t.Fail()
+	}
+	info, err := os.ReadFile(filepath.Join(trashInfoDir(trashDir()), "my file%.txt.trashinfo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(info), "Path="+filepath.Join(tmp, "my%20file%25.txt")+"\n") {
+		t.Fail()
+	}
+
+	// Same name again gets a new trash name
+	os.WriteFile(file, []byte("content 2"), 0644)
+	if err := moveToTrash(file); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := listTrash(trashDirs())
+	if err != nil || len(entries) != 2 {
+		t.Fatal(entries, err)
+	}
+
+	restored, err := restoreFromTrash(trashDir(), "my file%.txt.2")
+	if err != nil || restored != file {
+		t.Fatal(restored, err)
+	}
+	data, _ := os.ReadFile(file)
+	if string(data) != "content 2" {
+		t.Fail()
+	}
+	// Restoring over an existing file fails
+	if _, err := restoreFromTrash(trashDir(), "my file%.txt"); err == nil {
+		t.Fail()
+	}
+	if err := purgeFromTrash(trashDir(), "my file%.txt"); err != nil {
+		t.Fail()
+	}
+	entries, _ = listTrash(trashDirs())
+	if len(entries) != 0 {
+		t.Fail()
+	}
+}
+
+func TestTrashOnTopDir(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", filepath.Join(tmp, "data"))
+	topDir := filepath.Join(tmp, "mount")
+	os.MkdirAll(filepath.Join(topDir, "sub"), 0755)
+	file := filepath.Join(topDir, "sub", "a.txt")
+	os.WriteFile(file, []byte("content"), 0644)
+
+	trash := filepath.Join(topDir, ".Trash-"+strconv.Itoa(os.Getuid()))
+	if err := makeTopDirTrash(trash); err != nil {
+		t.Fatal(err)
+	}
+	if err := moveToTrashDir(file, trash, topDir); err != nil {
+		t.Fatal(err)
+	}
+	info, _ := os.ReadFile(filepath.Join(trashInfoDir(trash), "a.txt.trashinfo"))
+	// The path is relative to the top directory
+	if !strings.Contains(string(info), "Path=sub/a.txt\n") {
+		t.Fatal(string(info))
+	}
+	if _, err := os.Lstat(filepath.Join(trashFilesDir(trash), "a.txt")); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := listTrash([]string{trashDir(), trash})
+	if err != nil || len(entries) != 1 || entries[0].originalPath != file || entries[0].trash != trash {
+		t.Fatal(entries, err)
+	}
+	restored, err := restoreFromTrash(trash, "a.txt")
+	if err != nil || restored != file {
+		t.Fatal(restored, err)
+	}
+
+	// $topdir/.Trash/$uid is used inside the .Trash of the administrator
+	adminTrash := filepath.Join(topDir, ".Trash", strconv.Itoa(os.Getuid()))
+	if trashTopDir(adminTrash) != topDir || trashTopDir(trash) != topDir {
+		t.Fail()
+	}
+	// A symlink is not accepted as trash
+	os.Symlink(tmp, filepath.Join(topDir, ".Trash-link"))
+	if makeTopDirTrash(filepath.Join(topDir, ".Trash-link")) == nil {
+		t.Fail()
+	}
+	// Files on the same filesystem as the home trash go to the home trash
+	if trash, topDir := trashDirFor(file); trash != trashDir() || topDir != "" {
+		t.Fail()
+	}
+}
+
+func TestQuitOnConfirm(t *testing.T) {
+	m := Model{width: 80, height: 20, path: t.TempDir()}
+	confirmed := false
+	m.askConfirmation("Delete?", func() error { confirmed = true; return nil })
+	_, cmd := m.update(tea.KeyMsg{Type: tea.KeyCtrlC})
+	if cmd == nil || confirmed {
+		t.Fatal(cmd, confirmed)
+	}
+	if _, ok := cmd().(tea.QuitMsg); !ok {
+		t.Fail()
+	}
+}
@@ -1,10 +1,65 @@
 package config
 
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
 var LIST_FOLDERS_FIRST = true
 var SHOW_DETAILS = true
+var SHOW_PREVIEW = false
+var PREVIEW_SIZE = 50 // Percent of the screen width
 var FILES_SEPARATOR_SZ = 2
 var DETAILS_SEPARATOR_SZ = 2
 var ADD_ONE_DOT_FOLDER = false
 var ADD_TWO_DOT_FOLDER = true
-var EDIT_FILE_CMD = `nano "%s"`
+var EDIT_FILE_CMD = `nano +%l %s` // %s is replaced by the quoted file name and %l by the line number
 var ADD_LAST_CMD_TO_HISTORY = true
+var SEARCH_ALGORITHM = "substring" // substring or fuzzy
+var FIND_MAX_DEPTH = 10
+var FIND_SKIP = ".git,node_modules" // Comma separated
+var FIND_GITIGNORE = true
+var SAVE_CURSORS = false // Save the focused item of each folder between runs
+var KEYMAP_PRESET = "default"
+var KEYMAP = map[string][]string{} // Action name -> keys, from the [keymap] table of the config file
+
+// EditFileCmd returns EDIT_FILE_CMD without the quotes around %s (like on the old default, nano "%s"),
+// as %s is replaced by the quoted file name
+func EditFileCmd() string {
+	return unquotePlaceholder(EDIT_FILE_CMD)
+}
+
+func unquotePlaceholder(cmd string) string {
+	return strings.NewReplacer(`"%s"`, "%s", `'%s'`, "%s").Replace(cmd)
+}
+
+// Dir returns the cd-surfer config directory ($XDG_CONFIG_HOME/cd-surfer)
+func Dir() string {
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" {
+		home, _ := os.UserHomeDir()
+		configHome = filepath.Join(home, ".config")
+	}
+	return filepath.Join(configHome, "cd-surfer")
+}
+
+// DataHome returns the base directory for user data ($XDG_DATA_HOME, usually ~/.local/share)
+func DataHome() string {
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		home, _ := os.UserHomeDir()
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+	return dataHome
+}
+
+// DataDir returns the cd-surfer data directory ($XDG_DATA_HOME/cd-surfer)
+func DataDir() string {
+	return filepath.Join(DataHome(), "cd-surfer")
+}
+
+// TemplatesDir returns the directory with the templates for new files
+func TemplatesDir() string {
+	return filepath.Join(Dir(), "templates")
+}
@@ -0,0 +1,317 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Options can be set, in increasing priority, on the config file ($XDG_CONFIG_HOME/cd-surfer/config.toml),
+// on environment variables (CDSURFER_<NAME>) and on command line flags (--<name>)
+
+type option struct {
+	name     string // Name on the config file
+	ptr      interface{}
+	help     string
+	validate func(v interface{}) error // Checks a value before it is set
+}
+
+var options = []option{
+	{name: "list_folders_first", ptr: &LIST_FOLDERS_FIRST, help: "list folders before files"},
+	{name: "show_details", ptr: &SHOW_DETAILS, help: "start on the detailed view"},
+	{name: "show_preview", ptr: &SHOW_PREVIEW, help: "start with the preview pane of the focused item"},
+	{name: "preview_size", ptr: &PREVIEW_SIZE, help: "width of the preview pane, in percent of the screen width", validate: func(v interface{}) error {
+		if v.(int) < 10 || v.(int) > 90 {
+			return fmt.Errorf("it must be between 10 and 90")
+		}
+		return nil
+	}},
+	{name: "files_separator_sz", ptr: &FILES_SEPARATOR_SZ, help: "spaces between columns", validate: validateNotNegative},
+	{name: "details_separator_sz", ptr: &DETAILS_SEPARATOR_SZ, help: "spaces between the details columns", validate: validateNotNegative},
+	{name: "add_one_dot_folder", ptr: &ADD_ONE_DOT_FOLDER, help: "list ./ (enter it to quit changing to the current folder)"},
+	{name: "add_two_dot_folder", ptr: &ADD_TWO_DOT_FOLDER, help: "list ../"},
+	{name: "edit_file_cmd", ptr: &EDIT_FILE_CMD, help: "command that opens files. %s is replaced by the quoted file name and %l by the line number", validate: func(v interface{}) error {
+		if !strings.Contains(v.(string), "%s") {
+			return fmt.Errorf("it must contain %%s, the file name placeholder")
+		}
+		if isPlaceholderQuoted(unquotePlaceholder(v.(string))) {
+			return fmt.Errorf("%%s must not be inside quotes, it is replaced by the quoted file name")
+		}
+		return nil
+	}},
+	{name: "add_last_cmd_to_history", ptr: &ADD_LAST_CMD_TO_HISTORY, help: "add the executed file or editor command to the shell history"},
+	{name: "search_algorithm", ptr: &SEARCH_ALGORITHM, help: "substring, or fuzzy to match the typed letters in order, like fzf", validate: func(v interface{}) error {
+		if v != "substring" && v != "fuzzy" {
+			return fmt.Errorf("expected substring or fuzzy, got %q", v)
+		}
+		return nil
+	}},
+	{name: "find_max_depth", ptr: &FIND_MAX_DEPTH, help: "levels of folders searched by the recursive search, 1 for only the current folder", validate: func(v interface{}) error {
+		if v.(int) < 1 {
+			return fmt.Errorf("it must be at least 1")
+		}
+		return nil
+	}},
+	{name: "find_skip", ptr: &FIND_SKIP, help: "comma separated folder names skipped by the recursive search"},
+	{name: "find_gitignore", ptr: &FIND_GITIGNORE, help: "skip the paths ignored by .gitignore files on the recursive search"},
+	{name: "save_cursors", ptr: &SAVE_CURSORS, help: "save the focused item and scroll of each folder between runs"},
+	{name: "keymap_preset", ptr: &KEYMAP_PRESET, help: "key bindings preset: default, vim or emacs. The [keymap] table overrides it"},
+}
+
+func validateNotNegative(v interface{}) error {
+	if v.(int) < 0 {
+		return fmt.Errorf("it must not be negative")
+	}
+	return nil
+}
+
+// isPlaceholderQuoted returns true if a %s of cmd is inside quotes, like on "+%l %s"
+func isPlaceholderQuoted(cmd string) bool {
+	quote := byte(0)
+	for ix := 0; ix < len(cmd); ix++ {
+		c := cmd[ix]
+		switch {
+		case c == '\\' && quote != '\'':
+			ix++ // Escaped character
+		case quote == 0 && (c == '"' || c == '\''):
+			quote = c
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote != 0 && strings.HasPrefix(cmd[ix:], "%s"):
+			return true
+		}
+	}
+	return false
+}
+
+// Path returns the config file path
+func Path() string {
+	return filepath.Join(Dir(), "config.toml")
+}
+
+func findOption(name string) (option, bool) {
+	for _, o := range options {
+		if o.name == name {
+			return o, true
+		}
+	}
+	return option{}, false
+}
+
+func optionNames() string {
+	names := []string{}
+	for _, o := range options {
+		names = append(names, o.name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// Set parses value as the type of the option name and sets it
+func Set(name, value string) error {
+	o, found := findOption(name)
+	if !found {
+		return fmt.Errorf("unknown option %q. Valid options: %s", name, optionNames())
+	}
+	var v interface{} = value
+	var err error
+	switch o.ptr.(type) {
+	case *bool:
+		if v, err = strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("%s: expected true or false, got %q", name, value)
+		}
+	case *int:
+		if v, err = strconv.Atoi(value); err != nil {
+			return fmt.Errorf("%s: expected an integer, got %q", name, value)
+		}
+	}
+	return assign(o, v)
+}
+
+// assign validates v and sets it on the option. An invalid value leaves the option unchanged
+func assign(o option, v interface{}) error {
+	if o.validate != nil {
+		if err := o.validate(v); err != nil {
+			return fmt.Errorf("%s: %w", o.name, err)
+		}
+	}
+	switch ptr := o.ptr.(type) {
+	case *bool:
+		*ptr = v.(bool)
+	case *int:
+		*ptr = v.(int)
+	case *string:
+		*ptr = v.(string)
+	}
+	return nil
+}
+
+// LoadFile loads the options of a TOML file. A missing file is not an error
+func LoadFile(path string) error {
+	values := map[string]interface{}{}
+	_, err := toml.DecodeFile(path, &values)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if name == "keymap" {
+			if err := setKeymap(values[name]); err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+			continue
+		}
+		if err := setValue(name, values[name]); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+func setValue(name string, value interface{}) error {
+	o, found := findOption(name)
+	if !found {
+		return fmt.Errorf("unknown option %q. Valid options: %s", name, optionNames())
+	}
+	switch o.ptr.(type) {
+	case *bool:
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%s: expected true or false, got %v", name, value)
+		}
+	case *int:
+		v, ok := value.(int64)
+		if !ok {
+			return fmt.Errorf("%s: expected an integer, got %v", name, value)
+		}
+		value = int(v)
+	case *string:
+		if _, ok := value.(string); !ok {
+			return fmt.Errorf("%s: expected a string, got %v", name, value)
+		}
+	}
+	return assign(o, value)
+}
+
+// setKeymap reads the [keymap] table. Each action maps to a key or a list of keys. An empty list
+// unbinds the action
+func setKeymap(value interface{}) error {
+	table, ok := value.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("keymap: expected a table, like [keymap]")
+	}
+	for action, keys := range table {
+		switch keys := keys.(type) {
+		case string:
+			KEYMAP[action] = []string{keys}
+		case []interface{}:
+			KEYMAP[action] = []string{}
+			for _, k := range keys {
+				s, ok := k.(string)
+				if !ok {
+					return fmt.Errorf("keymap.%s: expected a key or a list of keys, got %v", action, k)
+				}
+				KEYMAP[action] = append(KEYMAP[action], s)
+			}
+		default:
+			return fmt.Errorf("keymap.%s: expected a key or a list of keys, got %v", action, keys)
+		}
+	}
+	return nil
+}
+
+// EnvName returns the environment variable of an option
+func EnvName(name string) string {
+	return "CDSURFER_" + strings.ToUpper(name)
+}
+
+// LoadEnv loads the options set on environment variables
+func LoadEnv() error {
+	for _, o := range options {
+		value, found := os.LookupEnv(EnvName(o.name))
+		if !found {
+			continue
+		}
+		if err := Set(o.name, value); err != nil {
+			return fmt.Errorf("%s: %w", EnvName(o.name), err)
+		}
+	}
+	return nil
+}
+
+func flagName(name string) string {
+	return strings.ReplaceAll(name, "_", "-")
+}
+
+// flagValue keeps the flag value, to be applied after the config file and the environment variables
+type flagValue struct {
+	value  string
+	isBool bool
+}
+
+func (f *flagValue) String() string     { return f.value }
+func (f *flagValue) Set(v string) error { f.value = v; return nil }
+func (f *flagValue) IsBoolFlag() bool   { return f.isBool }
+
+// RegisterFlags adds a flag for each option. They are applied by ApplyFlags
+func RegisterFlags(fs *flag.FlagSet) {
+	for _, o := range options {
+		_, isBool := o.ptr.(*bool)
+		fs.Var(&flagValue{isBool: isBool}, flagName(o.name), o.help)
+	}
+}
+
+// ApplyFlags sets the options passed on the command line
+func ApplyFlags(fs *flag.FlagSet) error {
+	var err error
+	fs.Visit(func(f *flag.Flag) {
+		for _, o := range options {
+			if err == nil && flagName(o.name) == f.Name {
+				if setErr := Set(o.name, f.Value.String()); setErr != nil {
+					err = fmt.Errorf("--%s: %w", f.Name, setErr)
+				}
+			}
+		}
+	})
+	return err
+}
+
+// Load loads the config file, the environment variables and the flags, in this order
+func Load(fs *flag.FlagSet) error {
+	if err := LoadFile(Path()); err != nil {
+		return err
+	}
+	if err := LoadEnv(); err != nil {
+		return err
+	}
+	return ApplyFlags(fs)
+}
+
+// Dump returns the effective options, in the config file format
+func Dump() string {
+	out := ""
+	for _, o := range options {
+		out += "# " + o.help + "\n"
+		switch ptr := o.ptr.(type) {
+		case *bool:
+			out += fmt.Sprintf("%s = %t\n", o.name, *ptr)
+		case *int:
+			out += fmt.Sprintf("%s = %d\n", o.name, *ptr)
+		case *string:
+			out += fmt.Sprintf("%s = %s\n", o.name, strconv.Quote(*ptr))
+		}
+	}
+	return out
+}
@@ -0,0 +1,97 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadPrecedence(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", tmp)
+	os.MkdirAll(Dir(), 0755)
+	os.WriteFile(Path(), []byte("show_details = false\nfiles_separator_sz = 3\nedit_file_cmd = \"vim %s\"\n"), 0644)
+	t.Setenv("CDSURFER_FILES_SEPARATOR_SZ", "5")
+	t.Setenv("CDSURFER_SHOW_DETAILS", "false")
+	showDetails, filesSeparatorSz, editFileCmd := SHOW_DETAILS, FILES_SEPARATOR_SZ, EDIT_FILE_CMD
+	t.Cleanup(func() { SHOW_DETAILS, FILES_SEPARATOR_SZ, EDIT_FILE_CMD = showDetails, filesSeparatorSz, editFileCmd })
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	RegisterFlags(fs)
+	if err := fs.Parse([]string{"--show-details"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := Load(fs); err != nil {
+		t.Fatal(err)
+	}
+	if !SHOW_DETAILS || FILES_SEPARATOR_SZ != 5 || EDIT_FILE_CMD != "vim %s" {
+		t.Fail()
+	}
+	if !strings.Contains(Dump(), "edit_file_cmd = \"vim %s\"\n") {
+		t.Fail()
+	}
+}
+
+func TestLoadFileErrors(t *testing.T) {
+	tmp := t.TempDir()
+	showDetails, filesSeparatorSz, editFileCmd := SHOW_DETAILS, FILES_SEPARATOR_SZ, EDIT_FILE_CMD
+	t.Cleanup(func() { SHOW_DETAILS, FILES_SEPARATOR_SZ, EDIT_FILE_CMD = showDetails, filesSeparatorSz, editFileCmd })
+	cases := []string{
+		"unknown_option = 1",
+		"show_details = \"yes\"",
+		"files_separator_sz = -1",
+		"edit_file_cmd = \"nano\"",
+		"edit_file_cmd = \"nano \\\"+%l %s\\\"\"",
+		"edit_file_cmd = \"sh -c 'nano \\\"%s\\\"'\"",
+		"show_details = ",
+	}
+	for _, c := range cases {
+		path := filepath.Join(tmp, "config.toml")
+		os.WriteFile(path, []byte(c), 0644)
+		if err := LoadFile(path); err == nil {
+			t.Error(c)
+		}
+	}
+	if err := LoadFile(filepath.Join(tmp, "missing.toml")); err != nil {
+		t.Fail()
+	}
+	// Invalid values are not set
+	if FILES_SEPARATOR_SZ != filesSeparatorSz || EDIT_FILE_CMD != editFileCmd {
+		t.Fail()
+	}
+}
+
+func TestLoadEnvInvalidValue(t *testing.T) {
+	filesSeparatorSz := FILES_SEPARATOR_SZ
+	t.Cleanup(func() { FILES_SEPARATOR_SZ = filesSeparatorSz })
+	t.Setenv("CDSURFER_FILES_SEPARATOR_SZ", "-1")
+	if err := LoadEnv(); err == nil || FILES_SEPARATOR_SZ != filesSeparatorSz {
+		t.Fail()
+	}
+	if err := Set("search_algorithm", "regex"); err == nil || SEARCH_ALGORITHM != "substring" {
+		t.Fail()
+	}
+	if err := Set("find_max_depth", "0"); err == nil || FIND_MAX_DEPTH != 10 {
+		t.Fail()
+	}
+}
+
+func TestLoadFileKeymap(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	os.WriteFile(path, []byte("keymap_preset = \"vim\"\n[keymap]\nquit = \"alt+q\"\nsearch = [\"/\", \"ctrl+s\"]\ncopy = []\n"), 0644)
+	t.Cleanup(func() { KEYMAP = map[string][]string{}; KEYMAP_PRESET = "default" })
+	if err := LoadFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if KEYMAP_PRESET != "vim" || len(KEYMAP) != 3 || KEYMAP["quit"][0] != "alt+q" ||
+		len(KEYMAP["search"]) != 2 || KEYMAP["copy"] == nil || len(KEYMAP["copy"]) != 0 {
+		t.Fatal(KEYMAP)
+	}
+
+	os.WriteFile(path, []byte("[keymap]\nquit = 1\n"), 0644)
+	if err := LoadFile(path); err == nil {
+		t.Fail()
+	}
+}
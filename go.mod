@@ -3,12 +3,15 @@ module github.com/andriykrefer/cdsurfer
 go 1.18
 
 require (
+	github.com/BurntSushi/toml v1.3.2
 	github.com/andriykrefer/exp v0.0.0-20230708005456-3b6ef4018085
 	github.com/charmbracelet/bubbles v0.16.1
 	github.com/charmbracelet/bubbletea v0.24.2
 	github.com/charmbracelet/lipgloss v0.7.1
 	github.com/dustin/go-humanize v1.0.1
 	github.com/gookit/color v1.5.3
+	github.com/klauspost/compress v1.17.0
+	golang.org/x/sys v0.7.0
 )
 
 require (
@@ -25,7 +28,6 @@ require (
 	github.com/rivo/uniseg v0.2.0 // indirect
 	github.com/xo/terminfo v0.0.0-20210125001918-ca9a967f8778 // indirect
 	golang.org/x/sync v0.1.0 // indirect
-	golang.org/x/sys v0.7.0 // indirect
 	golang.org/x/term v0.6.0 // indirect
 	golang.org/x/text v0.3.8 // indirect
 )
@@ -1,10 +1,27 @@
 #!/usr/bin/env bash
 set -e
 
-shell_cfg_file="$HOME/.bashrc"
 latest_release_file=https://github.com/andriykrefer/cdsurfer/releases/latest/download/cd-surfer_linux_amd64
 fname="cd-surfer"
 
+# Detect the user shell and its config file
+shell_name="$(basename "${SHELL:-bash}")"
+case "$shell_name" in
+  zsh)
+    shell_cfg_file="$HOME/.zshrc"
+    init_line='eval "$(/bin/cd-surfer init zsh)"     # cd-surfer'
+    ;;
+  fish)
+    shell_cfg_file="$HOME/.config/fish/config.fish"
+    init_line='/bin/cd-surfer init fish | source      # cd-surfer'
+    ;;
+  *)
+    shell_name="bash"
+    shell_cfg_file="$HOME/.bashrc"
+    init_line='eval "$(/bin/cd-surfer init bash)"    # cd-surfer'
+    ;;
+esac
+
 echo "Downloading latest release..."
 rm -rf /tmp/$fname
 wget -q -O /tmp/$fname $latest_release_file -O /tmp/$fname
@@ -13,22 +30,19 @@ echo "Installing in /bin/${fname}"
 sudo chmod 755 /tmp/$fname
 sudo mv /tmp/$fname /bin/
 
-# Make a backup .bashrc
+# Make a backup of the shell config
+mkdir -p "$(dirname "$shell_cfg_file")"
+touch "$shell_cfg_file"
 cp ${shell_cfg_file} "$shell_cfg_file.bk"
 
 # Remove old config
 sed '/# cd-surfer/d' "$shell_cfg_file.bk" > ${shell_cfg_file}
 
 # Add config
-printf 'function cds {                     # cd-surfer\n' >> ${shell_cfg_file}
-printf '  eval "$(/bin/cd-surfer "$@")"    # cd-surfer\n' >> ${shell_cfg_file}
-printf '}                                  # cd-surfer\n' >> ${shell_cfg_file}
-
-# Reload the current shell
-source $shell_cfg_file
+printf '%s\n' "$init_line" >> ${shell_cfg_file}
 
 echo "*************************************************************"
-echo "* cd-surfer installed succcesfully!                         *"
+echo "* cd-surfer installed succcesfully for ${shell_name}!"
 echo "* Restart open terminals to take effect                     *"
 echo "* Enter the command 'cds' in the terminal to call cd-surfer *"
 echo "*************************************************************"